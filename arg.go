@@ -13,6 +13,13 @@ type Arg struct {
 	// Required sets the argument to generate an error when absent.
 	Required bool
 
+	// Min and Max bound the number of values an aggregate argument accepts.
+	// A positive Min implies Required and may not exceed Max, and a Max of
+	// zero is unbounded. Non-aggregate arguments must leave both zero. Values
+	// are reserved for any arguments following an aggregate, so for example
+	// "cp SRC... DST" assigns the last value to DST.
+	Min, Max int
+
 	// PreAction is invoked after parsing, but before values are set. All pre-actions
 	// are executed unconditionally in the order encountered during parsing.
 	PreAction Action
@@ -34,3 +41,24 @@ func (a *Arg) setValue(s string) error {
 	}
 	return nil
 }
+
+// minValues returns the fewest values the argument must be given.
+func (a *Arg) minValues() int {
+	switch {
+	case IsAggregate(a.Value) && a.Min > 0:
+		return a.Min
+	case a.Required:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// maxValues returns the most values the argument may be given, or zero if
+// there's no limit.
+func (a *Arg) maxValues() int {
+	if IsAggregate(a.Value) {
+		return a.Max
+	}
+	return 1
+}
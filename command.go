@@ -14,6 +14,10 @@ type Command struct {
 	// Name of the command.
 	Name string
 
+	// Aliases are optional alternate names for the command. They are accepted
+	// anywhere the command's name is, and are displayed alongside it in usage.
+	Aliases []string
+
 	// Text describing the command. It may be a single line or an arbitrarily
 	// long description. Usage writers may assume the first line can serve
 	// independently as a short-form description.
@@ -22,21 +26,61 @@ type Command struct {
 	// Hidden sets whether the command should be omitted from usage text.
 	Hidden bool
 
+	// DefaultCommand optionally names a subcommand to invoke when none is given.
+	// The subcommand's flags, arguments, and action then apply as if it had
+	// been named explicitly, and its flags imply it when given first. It may
+	// be the name or an alias of any subcommand; naming anything else causes
+	// Parse to panic.
+	DefaultCommand string
+
+	// StrictOrdering sets whether flags must precede positional arguments. If
+	// set, everything following the command's first positional argument is
+	// treated as an argument, even if it looks like a flag. A first "--" still
+	// starts any passthrough. This is implied for all commands when the
+	// POSIXLY_CORRECT environment variable is set.
+	StrictOrdering bool
+
+	// Passthrough optionally collects every argument following "--" verbatim,
+	// rather than matching them to positional arguments or subcommands.
+	Passthrough *[]string
+
 	// PreAction is invoked after parsing, but before values are set. All pre-actions
 	// are executed unconditionally in the order encountered during parsing.
 	PreAction Action
 
 	// Action invoked after parsing and argument validation. Only the active
-	// context, i.e. the last command parsed, is invoked.
+	// context, i.e. the last command parsed, is invoked. A command group with no
+	// action requires a subcommand.
 	Action Action
 
 	// Client-defined labels for grouping and processing commands.
 	Labels map[string]string
 
-	parent   *Command
-	commands []*Command
-	flags    []*Flag
-	args     []*Arg
+	// AllowAbbreviations sets whether long flags and subcommands may be given
+	// as any unambiguous prefix of their names, such as "--verb" for
+	// "--verbose". Hidden flags and commands must always be named in full.
+	// This is only honored on the root command.
+	AllowAbbreviations bool
+
+	// Dialect selects the command-line syntax. The default is GNUDialect. This
+	// is only honored on the root command.
+	Dialect Dialect
+
+	// Repeats sets what happens when a flag with a non-aggregate value is
+	// given more than once, unless the flag sets its own policy. The default
+	// is LastWins. This is only honored on the root command.
+	Repeats RepeatPolicy
+
+	// ReportAllErrors sets whether validation continues past the first invalid
+	// value or missing requirement. When set, all such problems are returned
+	// together as ValidationErrors. This is only honored on the root command.
+	ReportAllErrors bool
+
+	parent      *Command
+	commands    []*Command
+	flags       []*Flag
+	args        []*Arg
+	constraints []constraint
 }
 
 // FullName returns a command's fully qualified name.
@@ -47,6 +91,11 @@ func (c *Command) FullName() string {
 	return c.parent.FullName() + " " + c.Name
 }
 
+// Names returns a command's name followed by its aliases, if any.
+func (c *Command) Names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
 // Parent returns a command's parent command, if any.
 func (c *Command) Parent() *Command { return c.parent }
 
@@ -77,6 +126,12 @@ func (c *Command) AddFlags(flags ...*Flag) {
 		if flag.Name == "" && flag.Short == rune(0) {
 			panic("flags may not be anonymous")
 		}
+		if flag.Arity > 1 && (flag.Value == nil || IsBoolean(flag.Value) || flag.ImplicitValue != "") {
+			panic("flags with multiple values may not be valueless, boolean, or implicit")
+		}
+		if flag.Arity > 1 && !IsAggregate(flag.Value) {
+			panic("flags with multiple values must be aggregate")
+		}
 		c.flags = append(c.flags, flag)
 	}
 }
@@ -96,16 +151,80 @@ func (c *Command) FullFlags() []*Flag {
 }
 
 // AddArgs creates a new positional argument under a command.
-func (c *Command) AddArgs(args ...*Arg) { c.args = append(c.args, args...) }
+func (c *Command) AddArgs(args ...*Arg) {
+	for _, arg := range args {
+		if (arg.Min != 0 || arg.Max != 0) && !IsAggregate(arg.Value) {
+			panic("only aggregate arguments may have value counts")
+		}
+		if arg.Min < 0 || arg.Max < 0 || arg.Max != 0 && arg.Min > arg.Max {
+			panic("argument value counts must satisfy 0 <= Min <= Max")
+		}
+		c.args = append(c.args, arg)
+	}
+}
 
 // Args returns a command's positional arguments, not including those of its parents.
 func (c *Command) Args() []*Arg {
 	return c.args[:]
 }
 
+// ExactlyOneOf declares that exactly one of the given flags is required.
+func (c *Command) ExactlyOneOf(flags ...*Flag) { c.addConstraint(exactlyOne, flags) }
+
+// AtMostOneOf declares that the given flags are mutually exclusive.
+func (c *Command) AtMostOneOf(flags ...*Flag) { c.addConstraint(atMostOne, flags) }
+
+// RequiredTogether declares that if any of the given flags is present, all of
+// them are required.
+func (c *Command) RequiredTogether(flags ...*Flag) { c.addConstraint(together, flags) }
+
+// Requires declares that a flag may only be given with all of its dependencies.
+func (c *Command) Requires(flag *Flag, dependencies ...*Flag) {
+	c.addConstraint(requires, append([]*Flag{flag}, dependencies...))
+}
+
+// RequiredUnless declares that a flag is required unless any of its
+// alternatives is given instead.
+func (c *Command) RequiredUnless(flag *Flag, alternatives ...*Flag) {
+	c.addConstraint(atLeastOne, append([]*Flag{flag}, alternatives...))
+}
+
+func (c *Command) addConstraint(kind constraintKind, flags []*Flag) {
+	if len(flags) < 2 {
+		panic("constraints must relate at least two flags")
+	}
+	c.constraints = append(c.constraints, constraint{kind, flags})
+}
+
 // Parse reads arguments and executes a command or one of its subcommands.
 func (c *Command) Parse(args []string) error {
+	return c.execute(newParser(c, args))
+}
+
+// ParseKnown is like Parse, but tolerates flags and arguments it doesn't
+// recognize. Unknown flags, including any values joined to them, and surplus
+// positional arguments are returned in their original order and form. This is
+// useful for wrappers which forward arguments to another program.
+//
+// An unknown flag can't be known to take a separate value, so any such value
+// is treated as a positional argument. Likewise, a value which doesn't name a
+// subcommand is returned rather than rejected, and later values may still
+// select a subcommand. Unknown short flags keep the remainder of their group,
+// e.g. "-xvf" is returned whole if "x" is unknown.
+//
+// Unless the command has a passthrough, "--" and everything after it are
+// returned as given. Under strict ordering, so is everything after the first
+// unrecognized argument.
+func (c *Command) ParseKnown(args []string) (rest []string, err error) {
 	parser := newParser(c, args)
+	parser.keepUnknown = true
+	err = c.execute(parser)
+	return parser.unknown, err
+}
+
+// execute parses arguments and invokes the resulting command.
+func (c *Command) execute(parser *parser) error {
+	c.checkDefaultCommands()
 	parsed, parseErr := parser.Parse()
 	context := parser.Context()
 
@@ -118,13 +237,40 @@ func (c *Command) Parse(args []string) error {
 		return parseErr
 	}
 
-	if err := setValues(context, parsed); err != nil {
+	if err := setValues(context, parsed, c.ReportAllErrors); err != nil {
 		return err
 	}
 
-	if c.Action != nil {
+	if context.Action != nil {
 		return context.Action(context)
 	}
+	if len(context.Commands()) != 0 {
+		return &MissingCommandError{Command: context}
+	}
+	return nil
+}
+
+// checkDefaultCommands panics if a command or any of its descendants names a
+// default command which isn't one of its children. This is checked before
+// parsing so misconfiguration is caught regardless of the arguments given.
+func (c *Command) checkDefaultCommands() {
+	if name := c.DefaultCommand; name != "" && c.lookupChild(name) == nil {
+		panic(fmt.Sprintf("default command %q is not a subcommand of %q", name, c.FullName()))
+	}
+	for _, cmd := range c.commands {
+		cmd.checkDefaultCommands()
+	}
+}
+
+// lookupChild returns the immediate child with a given name or alias, or nil.
+func (c *Command) lookupChild(name string) *Command {
+	for _, cmd := range c.commands {
+		for _, n := range cmd.Names() {
+			if n == name {
+				return cmd
+			}
+		}
+	}
 	return nil
 }
 
@@ -151,20 +297,50 @@ func invokePreActions(context *Command, parsed []entity) error {
 	return nil
 }
 
-func setValues(context *Command, parsed []entity) error {
+func setValues(context *Command, parsed []entity, reportAll bool) error {
 	type setter interface{ setValue(s string) error }
 
+	// Record errors, stopping at the first unless all are to be reported.
+	var errs ValidationErrors
+	fail := func(err error) bool {
+		errs = append(errs, err)
+		return !reportAll
+	}
+
 	// Set all values we saw during parsing.
 	seen := map[interface{}]bool{}
+	counts := map[*Arg]int{}
 	for _, e := range parsed {
 		val, ok := e.Option.(setter)
 		if !ok {
 			continue
 		}
 
+		// Values are reset when first set, clearing state from earlier parses.
+		if !seen[e.Option] {
+			switch option := e.Option.(type) {
+			case *Flag:
+				resetValue(option.Value)
+			case *Arg:
+				resetValue(option.Value)
+			}
+		}
+
 		seen[e.Option] = true
+		if arg, ok := e.Option.(*Arg); ok {
+			counts[arg]++
+		}
 		if err := val.setValue(e.Value); err != nil {
-			return fmt.Errorf("invalid value %q for %s: %s", e.Value, e.Name, err.Error())
+			invalid := &InvalidValueError{Value: e.Value, Token: e.Name, Command: context, Err: err}
+			switch option := e.Option.(type) {
+			case *Flag:
+				invalid.Flag = option
+			case *Arg:
+				invalid.Arg = option
+			}
+			if fail(invalid) {
+				return invalid
+			}
 		}
 	}
 
@@ -181,24 +357,102 @@ func setValues(context *Command, parsed []entity) error {
 				continue
 			}
 			if flag.Required {
-				return fmt.Errorf("missing required flag --%s", flag.Name)
+				err := &MissingRequiredError{Flag: flag, Command: context}
+				if fail(err) {
+					return err
+				}
+				continue
 			}
-			if err := applyDefault(flag.Value); err != nil {
+			if err := applyDefault(flag.Value); err != nil && fail(err) {
 				return err
 			}
 		}
 
 		for _, arg := range command.Args() {
 			if seen[arg] {
+				if counts[arg] < arg.minValues() {
+					err := &ArgCountError{Arg: arg, Count: counts[arg], Command: context}
+					if fail(err) {
+						return err
+					}
+				}
+				continue
+			}
+			if arg.minValues() != 0 {
+				err := &MissingRequiredError{Arg: arg, Command: context}
+				if fail(err) {
+					return err
+				}
 				continue
 			}
-			if arg.Required {
-				return fmt.Errorf("missing required argument %s", arg.Name)
+			if err := applyDefault(arg.Value); err != nil && fail(err) {
+				return err
 			}
-			if err := applyDefault(arg.Value); err != nil {
+		}
+	}
+
+	// Check combinations of flags last, since they're the least specific.
+	for i := len(stack) - 1; i >= 0; i-- {
+		for _, constraint := range stack[i].constraints {
+			if err := constraint.check(seen, context); err != nil && fail(err) {
 				return err
 			}
 		}
 	}
+
+	if len(errs) != 0 {
+		return errs
+	}
 	return nil
 }
+
+type constraintKind int
+
+const (
+	exactlyOne constraintKind = iota
+	atMostOne
+	atLeastOne
+	together
+	requires // The first flag requires the rest.
+)
+
+// constraint restricts the combinations in which a set of flags may be given.
+type constraint struct {
+	kind  constraintKind
+	flags []*Flag
+}
+
+func (c constraint) check(seen map[interface{}]bool, context *Command) error {
+	var given, missing []*Flag
+	for _, flag := range c.flags {
+		if seen[flag] {
+			given = append(given, flag)
+		} else {
+			missing = append(missing, flag)
+		}
+	}
+
+	var violated bool
+	switch c.kind {
+	case exactlyOne:
+		violated = len(given) != 1
+	case atMostOne:
+		violated = len(given) > 1
+	case atLeastOne:
+		violated = len(given) == 0
+	case together:
+		violated = len(given) != 0 && len(missing) != 0
+	case requires:
+		violated = seen[c.flags[0]] && len(missing) != 0
+		given = c.flags[:1]
+	}
+	if !violated {
+		return nil
+	}
+
+	// Exclusive flags given together are at fault regardless of what's missing.
+	if (c.kind == exactlyOne || c.kind == atMostOne) && len(given) > 1 {
+		return &ConstraintError{Given: given, Command: context}
+	}
+	return &ConstraintError{Given: given, Missing: missing, Command: context}
+}
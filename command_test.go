@@ -1,12 +1,25 @@
 package gargle
 
 import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	// Parsing honors POSIXLY_CORRECT, so tests mustn't inherit it.
+	os.Unsetenv("POSIXLY_CORRECT")
+	os.Exit(m.Run())
+}
+
 func TestAddCommand(t *testing.T) {
 	parent := &Command{Name: "root"}
 	child1 := &Command{Name: "first"}
@@ -24,7 +37,150 @@ func TestAddCommand(t *testing.T) {
 	assert.Panics(t, func() { parent.AddCommands(child2) }, "A command can't have multiple parents.")
 }
 
-// TODO: Test required flags/args.
+func TestParseRequired(t *testing.T) {
+	var s string
+	command := &Command{Name: "root"}
+	command.AddFlags(
+		&Flag{Name: "long", Required: true, Value: StringVar(&s)},
+		&Flag{Short: 's', Required: true, Value: StringVar(&s)},
+	)
+	command.AddArgs(&Arg{Name: "arg", Required: true, Value: StringVar(&s)})
+
+	cases := map[string]struct {
+		args []string
+		err  string
+	}{
+		"NoArgs":      {err: "missing required flag --long"},
+		"MissingLong": {args: []string{"-s", "x", "y"}, err: "missing required flag --long"},
+		"ShortOnly":   {args: []string{"--long", "x", "y"}, err: "missing required flag -s"},
+		"MissingArg":  {args: []string{"--long", "x", "-s", "y"}, err: "missing required argument arg"},
+		"AllPresent":  {args: []string{"--long", "x", "-s", "y", "z"}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := command.Parse(c.args)
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, c.err)
+			}
+		})
+	}
+}
+
+func TestParseReportAllErrors(t *testing.T) {
+	var i, j int
+	root := &Command{Name: "root", ReportAllErrors: true}
+	sub := &Command{Name: "sub"}
+	root.AddCommands(sub)
+	root.AddFlags(&Flag{Name: "root-req", Required: true})
+	sub.AddFlags(
+		&Flag{Name: "int", Value: IntVar(&i)},
+		&Flag{Short: 'r', Required: true},
+	)
+	sub.AddArgs(&Arg{Name: "count", Required: true, Value: IntVar(&j)})
+
+	t.Run("NoErrors", func(t *testing.T) {
+		assert.NoError(t, root.Parse([]string{"sub", "--root-req", "-r", "1"}))
+	})
+
+	t.Run("AllErrors", func(t *testing.T) {
+		err := root.Parse([]string{"sub", "--int=x", "y"})
+		expected := strings.Join([]string{
+			`invalid value "x" for --int: strconv.ParseInt: parsing "x": invalid syntax`,
+			`invalid value "y" for count: strconv.ParseInt: parsing "y": invalid syntax`,
+			`missing required flag --root-req`,
+			`missing required flag -r`,
+		}, "\n")
+		assert.EqualError(t, err, expected)
+
+		var errs ValidationErrors
+		require.True(t, errors.As(err, &errs))
+		assert.Len(t, errs, 4)
+
+		var missing *MissingRequiredError
+		assert.True(t, errors.As(err, &missing))
+	})
+
+	t.Run("MissingArg", func(t *testing.T) {
+		err := root.Parse([]string{"sub"})
+		assert.EqualError(t, err, "missing required flag --root-req\nmissing required flag -r\nmissing required argument count")
+	})
+}
+
+func TestParseErrorTypes(t *testing.T) {
+	var i int
+	root := &Command{Name: "root"}
+	sub := &Command{Name: "sub"}
+	root.AddCommands(sub)
+	intFlag := &Flag{Name: "int", Short: 'i', Value: IntVar(&i)}
+	reqFlag := &Flag{Name: "req", Required: true}
+	intArg := &Arg{Name: "arg", Value: IntVar(&i)}
+	sub.AddFlags(intFlag, reqFlag)
+	sub.AddArgs(intArg)
+
+	t.Run("UnknownFlag", func(t *testing.T) {
+		var err *UnknownFlagError
+		require.True(t, errors.As(root.Parse([]string{"-i"}), &err))
+		assert.Equal(t, "i", err.Name)
+		assert.Equal(t, "-i", err.Token)
+		assert.Equal(t, root, err.Command)
+		assert.Equal(t, []*Command{sub}, err.Owners)
+	})
+
+	t.Run("UnknownCommand", func(t *testing.T) {
+		var err *UnknownCommandError
+		require.True(t, errors.As(root.Parse([]string{"sbu"}), &err))
+		assert.Equal(t, "sbu", err.Name)
+		assert.Equal(t, root, err.Command)
+		assert.Equal(t, []string{"sub"}, err.Candidates)
+	})
+
+	t.Run("UnexpectedArgument", func(t *testing.T) {
+		var err *UnexpectedArgumentError
+		require.True(t, errors.As(root.Parse([]string{"sub", "--req", "1", "2"}), &err))
+		assert.Equal(t, "2", err.Value)
+		assert.Equal(t, sub, err.Command)
+	})
+
+	t.Run("MissingValue", func(t *testing.T) {
+		var err *MissingValueError
+		require.True(t, errors.As(root.Parse([]string{"sub", "--int"}), &err))
+		assert.Equal(t, "--int", err.Token)
+		assert.Equal(t, intFlag, err.Flag)
+		assert.Equal(t, sub, err.Command)
+	})
+
+	t.Run("InvalidFlagValue", func(t *testing.T) {
+		var err *InvalidValueError
+		require.True(t, errors.As(root.Parse([]string{"sub", "-ifoo"}), &err))
+		assert.Equal(t, "foo", err.Value)
+		assert.Equal(t, "-i", err.Token)
+		assert.Equal(t, intFlag, err.Flag)
+		assert.Nil(t, err.Arg)
+		assert.Equal(t, sub, err.Command)
+
+		var numErr *strconv.NumError
+		assert.True(t, errors.As(err, &numErr), "Cause should be unwrapped.")
+	})
+
+	t.Run("InvalidArgValue", func(t *testing.T) {
+		var err *InvalidValueError
+		require.True(t, errors.As(root.Parse([]string{"sub", "--req", "foo"}), &err))
+		assert.Equal(t, "arg", err.Token)
+		assert.Nil(t, err.Flag)
+		assert.Equal(t, intArg, err.Arg)
+	})
+
+	t.Run("MissingRequired", func(t *testing.T) {
+		var err *MissingRequiredError
+		require.True(t, errors.As(root.Parse([]string{"sub"}), &err))
+		assert.Equal(t, reqFlag, err.Flag)
+		assert.Nil(t, err.Arg)
+		assert.Equal(t, sub, err.Command)
+	})
+}
 
 func TestParseMinimal(t *testing.T) {
 	action := &testAction{}
@@ -113,6 +269,14 @@ func TestParseFlags(t *testing.T) {
 			args: []string{"--not-here"},
 			err:  "unknown flag: not-here",
 		},
+		"MisspelledLongFlag": {
+			args: []string{"--strign", "foo"},
+			err:  "unknown flag: strign; did you mean --string?",
+		},
+		"LongFlagAsShort": {
+			args: []string{"--b"},
+			err:  "unknown flag: b; did you mean -b?",
+		},
 		"UnknownShortFlag": {
 			args: []string{"-n"},
 			err:  "unknown flag: n",
@@ -217,7 +381,6 @@ func TestParseArgs(t *testing.T) {
 }
 
 func TestParseCommands(t *testing.T) {
-	var rootArg *string // Intentionally null; test should fail if this is ever set.
 	var flag int
 	var arg string
 
@@ -226,11 +389,10 @@ func TestParseCommands(t *testing.T) {
 	sub1Action := &testAction{}
 	sub1 := &Command{Name: "sub1", Action: sub1Action.Invoke}
 	sub2Action := &testAction{}
-	sub2 := &Command{Name: "sub2", Action: sub2Action.Invoke}
+	sub2 := &Command{Name: "sub2", Aliases: []string{"s2", "second"}, Action: sub2Action.Invoke}
 	subSubAction := &testAction{}
 	subSub := &Command{Name: "sub-sub", Action: subSubAction.Invoke}
 
-	root.AddArgs(&Arg{Name: "root-arg", Value: StringVar(rootArg)})
 	root.AddCommands(sub1, sub2)
 	sub1.AddFlags(&Flag{Name: "flag", Value: IntVar(&flag)})
 	sub1.AddCommands(subSub)
@@ -259,6 +421,11 @@ func TestParseCommands(t *testing.T) {
 			invoked: sub2Action,
 			context: sub2,
 		},
+		"LeafCommandAlias": {
+			args:    []string{"second"},
+			invoked: sub2Action,
+			context: sub2,
+		},
 		"MissingParentCommand": {
 			args: []string{"sub-sub"},
 			err:  `"root sub-sub" is not a valid command`,
@@ -274,6 +441,14 @@ func TestParseCommands(t *testing.T) {
 			args: []string{"missing"},
 			err:  `"root missing" is not a valid command`,
 		},
+		"MisspelledChild": {
+			args: []string{"sbu1"},
+			err:  `"root sbu1" is not a valid command; did you mean sub1?`,
+		},
+		"MisspelledChildAmbiguous": {
+			args: []string{"sub"},
+			err:  `"root sub" is not a valid command; did you mean sub1 or sub2?`,
+		},
 		"UnknownChildSubcommand": {
 			args: []string{"sub1", "missing"},
 			err:  `"root sub1 missing" is not a valid command`,
@@ -290,7 +465,7 @@ func TestParseCommands(t *testing.T) {
 		// Flag inheritance...
 		"UnknownChildFlag": {
 			args: []string{"--flag", "42"},
-			err:  "unknown flag: flag",
+			err:  `unknown flag: flag; --flag is a flag of "root sub1"`,
 		},
 		"ChildWithFlag": {
 			args:    []string{"sub1", "--flag=27"},
@@ -300,11 +475,11 @@ func TestParseCommands(t *testing.T) {
 		},
 		"ChildWithoutFlag": {
 			args: []string{"sub2", "--flag=42"},
-			err:  "unknown flag: flag",
+			err:  `unknown flag: flag; --flag is a flag of "root sub1"`,
 		},
 		"MisorderedChildFlag": {
 			args: []string{"--flag=42", "sub1"},
-			err:  "unknown flag: flag",
+			err:  `unknown flag: flag; --flag is a flag of "root sub1"`,
 		},
 		"FlagBetweenCommands": {
 			args:    []string{"sub1", "--flag", "137", "sub-sub"},
@@ -352,6 +527,1151 @@ func TestParseCommands(t *testing.T) {
 	}
 }
 
+func TestParseAbbreviations(t *testing.T) {
+	var verbose, version, hidden bool
+	var value string
+
+	root := &Command{Name: "root", AllowAbbreviations: true}
+	status := &Command{Name: "status"}
+	start := &Command{Name: "start"}
+	remove := &Command{Name: "remove", Aliases: []string{"remove-all"}}
+	secret := &Command{Name: "secret", Hidden: true}
+	root.AddCommands(status, start, remove, secret)
+	root.AddFlags(
+		&Flag{Name: "verbose", Value: BoolVar(&verbose)},
+		&Flag{Name: "version", Value: BoolVar(&version)},
+		&Flag{Name: "name", Value: StringVar(&value)},
+		&Flag{Name: "hidden", Hidden: true, Value: BoolVar(&hidden)},
+	)
+
+	var context *Command
+	for _, c := range []*Command{root, status, start, remove, secret} {
+		c.Action = func(c *Command) error {
+			context = c
+			return nil
+		}
+	}
+
+	cases := map[string]struct {
+		args    []string
+		err     string
+		context *Command
+		verbose bool
+		value   string
+	}{
+		"ExactCommand":    {args: []string{"start"}, context: start},
+		"CommandPrefix":   {args: []string{"stat"}, context: status},
+		"SharedAliases":   {args: []string{"rem"}, context: remove},
+		"FlagPrefix":      {args: []string{"--verb", "--na=foo", "star"}, context: start, verbose: true, value: "foo"},
+		"HiddenCommand":   {args: []string{"secret"}, context: secret},
+		"AmbiguousFlag":   {args: []string{"--ver"}, err: "ambiguous flag: ver; could be --verbose or --version"},
+		"HiddenFlag":      {args: []string{"--hid"}, err: "unknown flag: hid"},
+		"AmbiguousCmd":    {args: []string{"st"}, err: `"root st" is an ambiguous command; could be start or status`},
+		"HiddenPrefix":    {args: []string{"sec"}, err: `"root sec" is not a valid command`},
+		"AbbreviatedName": {args: []string{"--nam"}, err: "--name requires a value"},
+	}
+
+	for name, c := range cases {
+		context = nil
+		verbose = false
+		value = ""
+
+		t.Run(name, func(t *testing.T) {
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.context, context)
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.value, value)
+		})
+	}
+}
+
+func TestParseArgCounts(t *testing.T) {
+	var srcs, extra []string
+	var dst string
+
+	cp := &Command{Name: "cp"}
+	cp.AddArgs(
+		&Arg{Name: "src", Required: true, Value: StringsVar(&srcs)},
+		&Arg{Name: "dst", Required: true, Value: StringVar(&dst)},
+	)
+	pair := &Command{Name: "pair"}
+	pair.AddArgs(
+		&Arg{Name: "src", Min: 2, Max: 3, Value: StringsVar(&srcs)},
+		&Arg{Name: "extra", Value: StringsVar(&extra)},
+	)
+	upto := &Command{Name: "upto"}
+	upto.AddArgs(&Arg{Name: "src", Max: 2, Value: StringsVar(&srcs)})
+	root := &Command{Name: "app"}
+	root.AddFlags(&Flag{Name: "force", Short: 'f'})
+	root.AddCommands(cp, pair, upto)
+
+	cases := map[string]struct {
+		args  []string
+		err   string
+		srcs  []string
+		dst   string
+		extra []string
+	}{
+		"Trailing":        {args: []string{"cp", "a", "b", "c"}, srcs: []string{"a", "b"}, dst: "c"},
+		"TrailingOne":     {args: []string{"cp", "a", "b"}, srcs: []string{"a"}, dst: "b"},
+		"TrailingFlags":   {args: []string{"cp", "a", "-f", "b", "c"}, srcs: []string{"a", "b"}, dst: "c"},
+		"TrailingMissing": {args: []string{"cp", "a"}, err: "missing required argument src"},
+		"MinMax":          {args: []string{"pair", "a", "b", "c", "d"}, srcs: []string{"a", "b", "c"}, extra: []string{"d"}},
+		"BelowMin":        {args: []string{"pair", "a"}, err: "argument src requires at least 2 values; got 1"},
+		"BelowMinAbsent":  {args: []string{"pair"}, err: "missing required argument src"},
+		"Max":             {args: []string{"upto", "a", "b"}, srcs: []string{"a", "b"}},
+		"AboveMax":        {args: []string{"upto", "a", "b", "c"}, err: `unexpected argument: "c"`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			srcs, extra, dst = nil, nil, ""
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.srcs, srcs)
+			assert.Equal(t, c.dst, dst)
+			assert.Equal(t, c.extra, extra)
+		})
+	}
+
+	assert.Panics(t, func() { root.AddArgs(&Arg{Name: "n", Min: 3, Max: 2, Value: StringsVar(&srcs)}) }, "Counts must be satisfiable.")
+	assert.Panics(t, func() { root.AddArgs(&Arg{Name: "n", Min: -1, Value: StringsVar(&srcs)}) }, "Counts can't be negative.")
+	assert.Panics(t, func() { root.AddArgs(&Arg{Name: "n", Max: 2, Value: StringVar(&dst)}) }, "Scalar args take one value.")
+}
+
+func TestParseConstraints(t *testing.T) {
+	newTree := func() *Command {
+		json := &Flag{Name: "json"}
+		yaml := &Flag{Name: "yaml"}
+		quiet := &Flag{Name: "quiet", Short: 'q'}
+		verbose := &Flag{Name: "verbose", Short: 'v'}
+		user := &Flag{Name: "user", Value: StringVar(new(string))}
+		password := &Flag{Name: "password", Value: StringVar(new(string))}
+		token := &Flag{Name: "token", Value: StringVar(new(string))}
+		follow := &Flag{Name: "follow"}
+		input := &Flag{Name: "input", Value: StringVar(new(string))}
+		stdin := &Flag{Name: "stdin"}
+
+		root := &Command{Name: "app", ReportAllErrors: true}
+		root.AddFlags(quiet, verbose)
+		root.AtMostOneOf(quiet, verbose)
+
+		sub := &Command{Name: "sub", Action: func(*Command) error { return nil }}
+		sub.AddFlags(json, yaml, user, password, token, follow, input, stdin)
+		sub.ExactlyOneOf(json, yaml)
+		sub.RequiredTogether(user, password, token)
+		sub.Requires(follow, verbose)
+		sub.RequiredUnless(input, stdin)
+		root.AddCommands(sub)
+		return root
+	}
+
+	cases := map[string]struct {
+		args []string
+		err  string
+	}{
+		"Satisfied":         {args: []string{"sub", "--json", "--stdin"}},
+		"AllTogether":       {args: []string{"sub", "--json", "--stdin", "--user=a", "--password=b", "--token=c"}},
+		"Dependency":        {args: []string{"-v", "sub", "--json", "--stdin", "--follow"}},
+		"Alternative":       {args: []string{"sub", "--json", "--input=x"}},
+		"Exclusive":         {args: []string{"sub", "--json", "--yaml", "--stdin"}, err: "--json and --yaml can't be used together"},
+		"ExclusiveParent":   {args: []string{"-qv", "sub", "--json", "--stdin"}, err: "--quiet and --verbose can't be used together"},
+		"NoneOfExactlyOne":  {args: []string{"sub", "--stdin"}, err: "one of --json or --yaml is required"},
+		"PartlyTogether":    {args: []string{"sub", "--json", "--stdin", "--user=a"}, err: "--user requires --password and --token"},
+		"MostlyTogether":    {args: []string{"sub", "--json", "--stdin", "--user=a", "--token=c"}, err: "--user and --token require --password"},
+		"MissingDependency": {args: []string{"sub", "--json", "--stdin", "--follow"}, err: "--follow requires --verbose"},
+		"RequiredUnless":    {args: []string{"sub", "--json"}, err: "one of --input or --stdin is required"},
+		"Several":           {args: []string{"sub", "--follow"}, err: "one of --json or --yaml is required\n--follow requires --verbose\none of --input or --stdin is required"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := newTree().Parse(c.args)
+			if c.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, c.err)
+			var constraintErr *ConstraintError
+			assert.True(t, errors.As(err, &constraintErr))
+		})
+	}
+
+	assert.Panics(t, func() { newTree().ExactlyOneOf(&Flag{Name: "x"}) }, "Constraints need several flags.")
+}
+
+func TestParseCommandsWithArgs(t *testing.T) {
+	var branch string
+	var paths []string
+	checkoutAction := &testAction{}
+	checkout := &Command{Name: "checkout", Action: checkoutAction.Invoke, DefaultCommand: "status"}
+	statusAction := &testAction{}
+	status := &Command{Name: "status", Action: statusAction.Invoke}
+	checkout.AddCommands(status)
+	checkout.AddArgs(
+		&Arg{Name: "branch", Value: StringVar(&branch)},
+		&Arg{Name: "paths", Value: StringsVar(&paths)},
+	)
+
+	cases := map[string]struct {
+		args    []string
+		err     string
+		invoked *testAction
+		branch  string
+		paths   []string
+	}{
+		"NoArgs":          {invoked: statusAction},
+		"Command":         {args: []string{"status"}, invoked: statusAction},
+		"Arg":             {args: []string{"master"}, invoked: checkoutAction, branch: "master"},
+		"CommandAfterArg": {args: []string{"master", "status"}, invoked: checkoutAction, branch: "master", paths: []string{"status"}},
+		"ManyArgs":        {args: []string{"dev", "a", "b"}, invoked: checkoutAction, branch: "dev", paths: []string{"a", "b"}},
+	}
+
+	for name, c := range cases {
+		checkoutAction.Reset()
+		statusAction.Reset()
+		branch = ""
+		paths = nil
+
+		t.Run(name, func(t *testing.T) {
+			err := checkout.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, c.invoked.Result)
+			assert.Equal(t, c.branch, branch)
+			assert.Equal(t, c.paths, paths)
+		})
+	}
+
+	t.Run("ExhaustedArgs", func(t *testing.T) {
+		cmd := &Command{Name: "cmd"}
+		cmd.AddCommands(&Command{Name: "sub"})
+		cmd.AddArgs(&Arg{Name: "arg", Value: StringVar(new(string))})
+		assert.EqualError(t, cmd.Parse([]string{"one", "sub"}), `unexpected argument: "sub"`)
+	})
+}
+
+func TestParsePassthrough(t *testing.T) {
+	var verbose bool
+	var arg string
+	var rest []string
+
+	root := &Command{Name: "root"}
+	exec := &Command{Name: "exec", Passthrough: &rest, Action: func(*Command) error { return nil }}
+	root.AddCommands(exec)
+	root.AddFlags(&Flag{Name: "verbose", Short: 'v', Value: BoolVar(&verbose)})
+	exec.AddArgs(&Arg{Name: "name", Value: StringVar(&arg)})
+	exec.AddCommands(&Command{Name: "sub"})
+
+	cases := map[string]struct {
+		args    []string
+		strict  bool
+		verbose bool
+		arg     string
+		rest    []string
+	}{
+		"NoPassthrough":    {args: []string{"exec", "-v", "foo"}, verbose: true, arg: "foo"},
+		"EmptyPassthrough": {args: []string{"exec", "foo", "--"}, arg: "foo"},
+		"Passthrough": {
+			args:    []string{"exec", "-v", "--", "cmd", "--flag", "-v", "sub", "--"},
+			verbose: true,
+			rest:    []string{"cmd", "--flag", "-v", "sub", "--"},
+		},
+		"ArgsAndPassthrough": {
+			args: []string{"exec", "foo", "--", "bar"},
+			arg:  "foo",
+			rest: []string{"bar"},
+		},
+		"StrictPassthrough": {
+			args:   []string{"exec", "foo", "--", "bar", "--"},
+			strict: true,
+			arg:    "foo",
+			rest:   []string{"bar", "--"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			verbose = false
+			arg = ""
+			rest = nil
+			exec.StrictOrdering = c.strict
+
+			require.NoError(t, root.Parse(c.args))
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.arg, arg)
+			assert.Equal(t, c.rest, rest)
+		})
+	}
+}
+
+func TestParseKnown(t *testing.T) {
+	var verbose bool
+	var target string
+
+	command := &Command{Name: "wrap"}
+	command.AddFlags(
+		&Flag{Name: "verbose", Short: 'v', Value: BoolVar(&verbose)},
+		&Flag{Name: "target", Short: 't', Value: StringVar(&target)},
+	)
+
+	cases := map[string]struct {
+		args    []string
+		err     string
+		rest    []string
+		verbose bool
+		target  string
+	}{
+		"NoArgs": {},
+		"AllKnown": {
+			args:    []string{"-v", "--target", "x"},
+			verbose: true, target: "x",
+		},
+		"UnknownLong": {
+			args:    []string{"--run", "TestFoo", "-v", "--count=3", "./..."},
+			rest:    []string{"--run", "TestFoo", "--count=3", "./..."},
+			verbose: true,
+		},
+		"UnknownShort": {
+			args:   []string{"-x", "-vt", "foo", "-ovalue", "-vx"},
+			rest:   []string{"-x", "-ovalue", "-x"},
+			target: "foo", verbose: true,
+		},
+		"UnknownShortGroup": {
+			args: []string{"-xvt", "foo"},
+			rest: []string{"-xvt", "foo"},
+		},
+		"Verbatim": {
+			args:    []string{"-v", "--", "-t", "foo"},
+			rest:    []string{"--", "-t", "foo"},
+			verbose: true,
+		},
+		"KnownErrors": {
+			args: []string{"--bogus", "--target"},
+			err:  "--target requires a value",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			verbose = false
+			target = ""
+
+			rest, err := command.ParseKnown(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.rest, rest)
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.target, target)
+		})
+	}
+
+	t.Run("Subcommands", func(t *testing.T) {
+		subAction := &testAction{}
+		root := &Command{Name: "wrap"}
+		sub := &Command{Name: "sub", Action: subAction.Invoke}
+		root.AddCommands(sub)
+
+		rest, err := root.ParseKnown([]string{"--x", "other", "sub", "y"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--x", "other", "y"}, rest)
+		assert.Equal(t, sub, subAction.Result)
+	})
+
+	t.Run("VerbatimArgs", func(t *testing.T) {
+		var args []string
+		root := &Command{Name: "wrap"}
+		root.AddArgs(&Arg{Name: "args", Value: StringsVar(&args)})
+
+		rest, err := root.ParseKnown([]string{"x", "--", "-y", "z"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--", "-y", "z"}, rest)
+		assert.Equal(t, []string{"x"}, args)
+	})
+
+	t.Run("StrictOrdering", func(t *testing.T) {
+		verbose = false
+		root := &Command{Name: "wrap", StrictOrdering: true}
+		root.AddFlags(&Flag{Name: "verbose", Short: 'v', Value: BoolVar(&verbose)})
+
+		rest, err := root.ParseKnown([]string{"-v", "x", "-v", "--bogus"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"x", "-v", "--bogus"}, rest)
+		assert.True(t, verbose)
+
+		verbose = false
+		rest, err = root.ParseKnown([]string{"x", "-v"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"x", "-v"}, rest)
+		assert.False(t, verbose, "Flags after an argument belong to it.")
+	})
+}
+
+func TestParseStrictOrdering(t *testing.T) {
+	var verbose bool
+	var script string
+	var scriptArgs []string
+
+	newRoot := func(strict bool) *Command {
+		root := &Command{Name: "app"}
+		run := &Command{Name: "run", StrictOrdering: strict}
+		root.AddCommands(run)
+		root.AddFlags(&Flag{Name: "verbose", Short: 'x', Value: BoolVar(&verbose)})
+		run.AddArgs(
+			&Arg{Name: "script", Value: StringVar(&script)},
+			&Arg{Name: "args", Value: StringsVar(&scriptArgs)},
+		)
+		return root
+	}
+
+	cases := map[string]struct {
+		args       []string
+		strict     bool
+		posix      bool
+		verbose    bool
+		scriptArgs []string
+	}{
+		"Interspersed": {
+			args:       []string{"run", "script.sh", "-x", "a"},
+			verbose:    true,
+			scriptArgs: []string{"a"},
+		},
+		"Strict": {
+			args:       []string{"run", "-x", "script.sh", "-x", "--verbose"},
+			strict:     true,
+			verbose:    true,
+			scriptArgs: []string{"-x", "--verbose"},
+		},
+		"PosixlyCorrect": {
+			args:       []string{"run", "script.sh", "-x", "a"},
+			posix:      true,
+			scriptArgs: []string{"-x", "a"},
+		},
+		"StrictVerbatim": {
+			args:       []string{"run", "script.sh", "--", "-x", "--"},
+			strict:     true,
+			scriptArgs: []string{"-x", "--"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			verbose = false
+			script = ""
+			scriptArgs = nil
+			if c.posix {
+				t.Setenv("POSIXLY_CORRECT", "")
+			}
+
+			require.NoError(t, newRoot(c.strict).Parse(c.args))
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, "script.sh", script)
+			assert.Equal(t, c.scriptArgs, scriptArgs)
+		})
+	}
+}
+
+func TestParseNumbers(t *testing.T) {
+	var one bool
+	var lines int
+	var offset float64
+	var scale int
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Short: '1', Value: BoolVar(&one)},
+		&Flag{Name: "offset", Value: Float64Var(&offset)},
+	)
+	scaleCmd := &Command{Name: "scale"}
+	scaleCmd.AddArgs(&Arg{Name: "n", Value: IntVar(&scale)})
+	headCmd := &Command{Name: "head"}
+	headCmd.AddFlags(&Flag{Name: "lines", Short: 'n', NumberShorthand: true, Value: IntVar(&lines)})
+	headCmd.AddArgs(&Arg{Name: "n", Value: IntVar(&scale)})
+	root.AddCommands(scaleCmd, headCmd)
+
+	cases := map[string]struct {
+		args   []string
+		err    string
+		one    bool
+		lines  int
+		offset float64
+		scale  int
+	}{
+		"NegativeArg":       {args: []string{"scale", "-3"}, scale: -3},
+		"NegativeHexArg":    {args: []string{"scale", "-0x10"}, scale: -16},
+		"NegativeFlagValue": {args: []string{"scale", "--offset", "-1.5e3"}, offset: -1500},
+		"DigitFlag":         {args: []string{"scale", "-1"}, one: true},
+		"DigitFlagGroup":    {args: []string{"scale", "-1", "-12"}, err: "unknown flag: 2"},
+		"Shorthand":         {args: []string{"head", "-20"}, lines: 20},
+		"ShorthandAndArg":   {args: []string{"head", "-20", "--", "-5"}, lines: 20, scale: -5},
+		"ShorthandInvalid":  {args: []string{"head", "-2.5"}, err: `invalid value "2.5" for --lines: strconv.ParseInt: parsing "2.5": invalid syntax`},
+		"ShorthandDigit":    {args: []string{"head", "-1"}, one: true},
+		"NoShorthand":       {args: []string{"scale", "-20"}, scale: -20},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			one, lines, offset, scale = false, 0, 0, 0
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.one, one)
+			assert.Equal(t, c.lines, lines)
+			assert.Equal(t, c.offset, offset)
+			assert.Equal(t, c.scale, scale)
+		})
+	}
+}
+
+func TestParseRepeats(t *testing.T) {
+	var user, env string
+	var tags []string
+	var verbose, lines int
+
+	newRoot := func(repeats RepeatPolicy) *Command {
+		root := &Command{Name: "app", Repeats: repeats}
+		root.AddFlags(
+			&Flag{Name: "name", Short: 'n', Value: StringVar(&user)},
+			&Flag{Name: "env", Repeats: RejectRepeats, Value: StringVar(&env)},
+			&Flag{Name: "tag", Value: StringsVar(&tags)},
+			&Flag{Name: "verbose", Short: 'v', Value: CountVar(&verbose)},
+			&Flag{Name: "lines", Short: 'l', NumberShorthand: true, Value: IntVar(&lines)},
+		)
+		return root
+	}
+
+	cases := map[string]struct {
+		repeats RepeatPolicy
+		args    []string
+		err     string
+		user    string
+		env     string
+		tags    []string
+		verbose int
+		lines   int
+	}{
+		"Default":         {args: []string{"--name", "a", "-n", "b"}, user: "b"},
+		"LastWins":        {repeats: LastWins, args: []string{"--name", "a", "-n", "b"}, user: "b"},
+		"FirstWins":       {repeats: FirstWins, args: []string{"--name", "a", "-nb", "--name=c"}, user: "a"},
+		"Reject":          {repeats: RejectRepeats, args: []string{"--name", "a", "-n", "b"}, err: "-n may only be given once"},
+		"RejectOnce":      {repeats: RejectRepeats, args: []string{"--name", "a"}, user: "a"},
+		"FlagReject":      {args: []string{"--env", "a", "--env=b"}, err: "--env may only be given once"},
+		"FlagOverride":    {repeats: FirstWins, args: []string{"--env", "a", "--env=b"}, err: "--env may only be given once"},
+		"Aggregate":       {repeats: RejectRepeats, args: []string{"--tag", "a", "--tag", "b"}, tags: []string{"a", "b"}},
+		"Count":           {repeats: RejectRepeats, args: []string{"-vv", "--verbose"}, verbose: 3},
+		"FirstWinsValues": {repeats: FirstWins, args: []string{"--name", "a", "--name"}, err: "--name requires a value"},
+		"NumberLastWins":  {args: []string{"-l", "5", "-20"}, lines: 20},
+		"NumberFirstWins": {repeats: FirstWins, args: []string{"-l", "5", "-20"}, lines: 5},
+		"NumberFirst":     {repeats: FirstWins, args: []string{"-20", "--lines=5"}, lines: 20},
+		"NumberReject":    {repeats: RejectRepeats, args: []string{"-l", "5", "-20"}, err: "--lines may only be given once"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			user, env, tags, verbose, lines = "", "", nil, 0, 0
+
+			err := newRoot(c.repeats).Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.user, user)
+			assert.Equal(t, c.env, env)
+			assert.Equal(t, c.tags, tags)
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.lines, lines)
+		})
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	var verbose int
+	var all bool
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "verbose", Short: 'v', Value: CountVar(&verbose)},
+		&Flag{Name: "all", Short: 'a', Value: BoolVar(&all)},
+	)
+
+	cases := map[string]struct {
+		args    []string
+		err     string
+		verbose int
+		all     bool
+	}{
+		"Absent":       {args: []string{}},
+		"Once":         {args: []string{"-v"}, verbose: 1},
+		"Grouped":      {args: []string{"-vvv"}, verbose: 3},
+		"Mixed":        {args: []string{"-vav", "-v"}, verbose: 3, all: true},
+		"Long":         {args: []string{"--verbose", "--verbose"}, verbose: 2},
+		"Explicit":     {args: []string{"--verbose=4"}, verbose: 4},
+		"ExplicitThen": {args: []string{"--verbose=4", "-v"}, verbose: 5},
+		"Reset":        {args: []string{"-vv", "--verbose=false"}},
+		"Invalid":      {args: []string{"--verbose=lots"}, err: `invalid value "lots" for --verbose: "lots" is neither a count nor a boolean`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			verbose, all = 0, false
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.all, all)
+		})
+	}
+}
+
+// testTextList is a text value opting into aggregation.
+type testTextList []string
+
+func (l *testTextList) IsAggregate() bool { return true }
+func (l *testTextList) UnmarshalText(text []byte) error {
+	*l = append(*l, strings.ToUpper(string(text)))
+	return nil
+}
+
+// testTextSwitch is a text value opting into boolean parsing.
+type testTextSwitch string
+
+func (s *testTextSwitch) IsBoolean() bool { return true }
+func (s *testTextSwitch) UnmarshalText(text []byte) error {
+	*s = testTextSwitch("switch:" + string(text))
+	return nil
+}
+
+func TestParseTypedValues(t *testing.T) {
+	parseRune := func(s string) (rune, error) {
+		if utf8.RuneCountInString(s) != 1 {
+			return 0, errors.New("expected one character")
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		return r, nil
+	}
+
+	var sep rune
+	var ports []uint16
+	var ip net.IP
+	var list testTextList
+	var sw testTextSwitch
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "sep", Value: Var(&sep, parseRune)},
+		&Flag{Name: "port", Value: SliceVar(&ports, func(s string) (uint16, error) {
+			n, err := strconv.ParseUint(s, 10, 16)
+			return uint16(n), err
+		})},
+		&Flag{Name: "ip", Value: TextVar(&ip)},
+		&Flag{Name: "list", Value: TextVar(&list)},
+		&Flag{Name: "switch", Value: TextVar(&sw)},
+	)
+
+	cases := map[string]struct {
+		args  []string
+		err   string
+		sep   rune
+		ports []uint16
+		ip    net.IP
+		list  testTextList
+		sw    testTextSwitch
+	}{
+		"Absent":       {},
+		"Var":          {args: []string{"--sep", "é"}, sep: 'é'},
+		"VarInvalid":   {args: []string{"--sep", "ab"}, err: `invalid value "ab" for --sep: expected one character`},
+		"Slice":        {args: []string{"--port", "80", "--port=443"}, ports: []uint16{80, 443}},
+		"SliceInvalid": {args: []string{"--port", "65536"}, err: `invalid value "65536" for --port: strconv.ParseUint: parsing "65536": value out of range`},
+		"Text":         {args: []string{"--ip", "10.0.0.1"}, ip: net.IPv4(10, 0, 0, 1)},
+		"TextInvalid":  {args: []string{"--ip", "x"}, err: `invalid value "x" for --ip: invalid IP address: x`},
+		"TextAgg":      {args: []string{"--list", "a", "--list", "b"}, list: testTextList{"A", "B"}},
+		"TextBool":     {args: []string{"--switch"}, sw: "switch:true"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			sep, ports, ip, list, sw = 0, nil, nil, nil, ""
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.sep, sep)
+			assert.Equal(t, c.ports, ports)
+			assert.True(t, c.ip.Equal(ip), "%v != %v", c.ip, ip)
+			assert.Equal(t, c.list, list)
+			assert.Equal(t, c.sw, sw)
+		})
+	}
+
+	ip = net.IPv4(127, 0, 0, 1)
+	assert.Equal(t, "127.0.0.1", TextVar(&ip).String())
+	assert.Equal(t, "[A B]", TextVar(&testTextList{"A", "B"}).String())
+	assert.Equal(t, "[80 443]", SliceVar(&[]int{80, 443}, strconv.Atoi).String())
+}
+
+func TestParseEnums(t *testing.T) {
+	type level string
+	const (
+		debug level = "debug"
+		info  level = "info"
+	)
+
+	var format string
+	var lvl level
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "format", Value: EnumVar(&format, "json", "yaml", "table")},
+		&Flag{Name: "level", Value: WithDefault(TypedEnumVar(&lvl, debug, info), "info")},
+	)
+
+	cases := map[string]struct {
+		args   []string
+		err    string
+		format string
+		level  level
+	}{
+		"Defaults":   {level: info},
+		"Valid":      {args: []string{"--format", "yaml", "--level=debug"}, format: "yaml", level: debug},
+		"Invalid":    {args: []string{"--format", "xml"}, err: `invalid value "xml" for --format: must be json, yaml, or table`},
+		"Suggested":  {args: []string{"--format", "ymal"}, err: `invalid value "ymal" for --format: must be json, yaml, or table; did you mean yaml?`},
+		"Typed":      {args: []string{"--level", "inf"}, err: `invalid value "inf" for --level: must be debug or info; did you mean info?`},
+		"IgnoreCase": {args: []string{"--format", "JSON"}, err: `invalid value "JSON" for --format: must be json, yaml, or table`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			format, lvl = "", ""
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.format, format)
+			assert.Equal(t, c.level, lvl)
+		})
+	}
+
+	assert.Panics(t, func() { EnumVar(&format) }, "Enums need choices.")
+}
+
+func TestParseSlices(t *testing.T) {
+	var bools []bool
+	var ints []int
+	var int64s []int64
+	var uints []uint
+	var uint64s []uint64
+	var floats []float64
+	var durations []time.Duration
+	var tags []string
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "bool", Value: BoolsVar(&bools)},
+		&Flag{Name: "int", Value: IntsVar(&ints)},
+		&Flag{Name: "int64", Value: Int64sVar(&int64s)},
+		&Flag{Name: "uint", Value: UintsVar(&uints)},
+		&Flag{Name: "uint64", Value: Uint64sVar(&uint64s)},
+		&Flag{Name: "float", Value: WithSeparator(Float64sVar(&floats), ",")},
+		&Flag{Name: "duration", Value: DurationsVar(&durations)},
+		&Flag{Name: "tags", Value: WithSeparator(StringsVar(&tags), ",")},
+	)
+
+	type values struct {
+		bools     []bool
+		ints      []int
+		int64s    []int64
+		uints     []uint
+		uint64s   []uint64
+		floats    []float64
+		durations []time.Duration
+		tags      []string
+	}
+	cases := map[string]struct {
+		args []string
+		err  string
+		want values
+	}{
+		"Absent": {},
+		"Each": {
+			args: []string{"--bool", "t", "--bool=false", "--int", "-1", "--int64=0x10", "--uint", "2", "--uint64", "3",
+				"--float", "1.5", "--duration", "1m", "--tags", "a"},
+			want: values{
+				bools:     []bool{true, false},
+				ints:      []int{-1},
+				int64s:    []int64{16},
+				uints:     []uint{2},
+				uint64s:   []uint64{3},
+				floats:    []float64{1.5},
+				durations: []time.Duration{time.Minute},
+				tags:      []string{"a"},
+			},
+		},
+		"Separated":       {args: []string{"--tags", "a,b,c", "--tags=d"}, want: values{tags: []string{"a", "b", "c", "d"}}},
+		"SeparatedEscape": {args: []string{"--tags", `a\,b,c`}, want: values{tags: []string{"a,b", "c"}}},
+		"SeparatedFloats": {args: []string{"--float", "1,2.5"}, want: values{floats: []float64{1, 2.5}}},
+		"InvalidBool":     {args: []string{"--bool", "x"}, err: `invalid value "x" for --bool: strconv.ParseBool: parsing "x": invalid syntax`},
+		"InvalidInt":      {args: []string{"--int", "x"}, err: `invalid value "x" for --int: strconv.ParseInt: parsing "x": invalid syntax`},
+		"InvalidInt64":    {args: []string{"--int64", "x"}, err: `invalid value "x" for --int64: strconv.ParseInt: parsing "x": invalid syntax`},
+		"InvalidUint":     {args: []string{"--uint", "-1"}, err: `invalid value "-1" for --uint: strconv.ParseUint: parsing "-1": invalid syntax`},
+		"InvalidUint64":   {args: []string{"--uint64", "x"}, err: `invalid value "x" for --uint64: strconv.ParseUint: parsing "x": invalid syntax`},
+		"InvalidFloat":    {args: []string{"--float", "1,x"}, err: `invalid value "1,x" for --float: strconv.ParseFloat: parsing "x": invalid syntax`},
+		"InvalidDuration": {args: []string{"--duration", "x"}, err: `invalid value "x" for --duration: time: invalid duration "x"`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			bools, ints, int64s, uints, uint64s, floats, durations, tags = nil, nil, nil, nil, nil, nil, nil, nil
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.want, values{bools, ints, int64s, uints, uint64s, floats, durations, tags})
+		})
+	}
+}
+
+func TestParseMaps(t *testing.T) {
+	var labels map[string]string
+	var sizes map[string]int
+	var weights map[string]float64
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "label", Short: 'l', Value: StringMapVar(&labels)},
+		&Flag{Name: "size", Value: WithSeparator(IntMapVar(&sizes), ",")},
+		&Flag{Name: "weight", Value: WithDefault(Float64MapVar(&weights), "a=0.5", "b=1")},
+	)
+
+	cases := map[string]struct {
+		args    []string
+		err     string
+		labels  map[string]string
+		sizes   map[string]int
+		weights map[string]float64
+	}{
+		"Absent":       {weights: map[string]float64{"a": 0.5, "b": 1}},
+		"Pairs":        {args: []string{"-l", "a=1", "--label=b=x=y", "--weight", "c=2"}, labels: map[string]string{"a": "1", "b": "x=y"}, weights: map[string]float64{"c": 2}},
+		"EmptyValue":   {args: []string{"-la="}, labels: map[string]string{"a": ""}, weights: map[string]float64{"a": 0.5, "b": 1}},
+		"Separated":    {args: []string{"--size", "a=1,b=2", "--size=c=3"}, sizes: map[string]int{"a": 1, "b": 2, "c": 3}, weights: map[string]float64{"a": 0.5, "b": 1}},
+		"NotSeparated": {args: []string{"-l", "a=1,b=2"}, labels: map[string]string{"a": "1,b=2"}, weights: map[string]float64{"a": 0.5, "b": 1}},
+		"Duplicate":    {args: []string{"-l", "a=1", "-l", "a=2"}, err: `invalid value "a=2" for -l: duplicate key "a"`},
+		"DuplicateSep": {args: []string{"--size", "a=1,a=2"}, err: `invalid value "a=1,a=2" for --size: duplicate key "a"`},
+		"NoValue":      {args: []string{"-l", "a"}, err: `invalid value "a" for -l: expected KEY=VALUE`},
+		"EmptyKey":     {args: []string{"-l", "=a"}, err: `invalid value "=a" for -l: empty key`},
+		"InvalidType":  {args: []string{"--size", "a=x"}, err: `invalid value "a=x" for --size: strconv.ParseInt: parsing "x": invalid syntax`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			labels, sizes, weights = nil, nil, nil
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.labels, labels)
+			assert.Equal(t, c.sizes, sizes)
+			assert.Equal(t, c.weights, weights)
+		})
+	}
+
+	t.Run("Preset", func(t *testing.T) {
+		labels, sizes, weights = map[string]string{"env": "prod", "team": "a"}, nil, nil
+		require.NoError(t, root.Parse([]string{"--label", "env=dev", "--weight", "a=2"}))
+		assert.Equal(t, map[string]string{"env": "dev", "team": "a"}, labels)
+		assert.Equal(t, map[string]float64{"a": 2}, weights)
+
+		// Keys are only tracked within a parse, so defaults don't conflict
+		// with keys given previously.
+		labels, weights = nil, nil
+		require.NoError(t, root.Parse([]string{"--label", "env=qa"}))
+		assert.Equal(t, map[string]string{"env": "qa"}, labels)
+		assert.Equal(t, map[string]float64{"a": 0.5, "b": 1}, weights)
+	})
+
+	t.Run("InvalidNotDuplicate", func(t *testing.T) {
+		sizes := map[string]int{}
+		root := &Command{Name: "app", ReportAllErrors: true}
+		root.AddFlags(&Flag{Name: "size", Value: IntMapVar(&sizes)})
+
+		err := root.Parse([]string{"--size", "a=x", "--size", "a=1"})
+		assert.EqualError(t, err, `invalid value "a=x" for --size: strconv.ParseInt: parsing "x": invalid syntax`)
+		assert.Equal(t, map[string]int{"a": 1}, sizes)
+	})
+}
+
+func TestSplitEscaped(t *testing.T) {
+	cases := map[string]struct {
+		s, sep   string
+		expected []string
+	}{
+		"Empty":           {"", ",", []string{""}},
+		"Single":          {"a", ",", []string{"a"}},
+		"Several":         {"a,b,,c", ",", []string{"a", "b", "", "c"}},
+		"LongSeparator":   {"a::b:c", "::", []string{"a", "b:c"}},
+		"Escaped":         {`a\,b,c`, ",", []string{"a,b", "c"}},
+		"EscapedEscape":   {`a\\,b`, ",", []string{`a\`, "b"}},
+		"UnescapedEscape": {`a\b`, ",", []string{`a\b`}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, splitEscaped(c.s, c.sep))
+		})
+	}
+}
+
+func TestParseArity(t *testing.T) {
+	var size []int
+	var env []string
+	var arg string
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "resize", Short: 'r', Arity: 2, Value: IntsVar(&size)},
+		&Flag{Name: "env", Arity: 2, Value: StringsVar(&env)},
+	)
+	root.AddArgs(&Arg{Name: "arg", Value: StringVar(&arg)})
+
+	cases := map[string]struct {
+		args []string
+		err  string
+		size []int
+		env  []string
+		arg  string
+	}{
+		"Long":     {args: []string{"--resize", "640", "480"}, size: []int{640, 480}},
+		"Short":    {args: []string{"-r", "640", "480", "file"}, size: []int{640, 480}, arg: "file"},
+		"Attached": {args: []string{"-r640", "480"}, size: []int{640, 480}},
+		"Assigned": {args: []string{"--resize=640", "480"}, size: []int{640, 480}},
+		"Repeated": {args: []string{"--env", "A", "1", "--env", "B", "-2"}, env: []string{"A", "1", "B", "-2"}},
+		"Verbatim": {args: []string{"--env", "--resize", "--"}, env: []string{"--resize", "--"}},
+		"Missing":  {args: []string{"--resize", "640"}, err: "--resize requires 2 values"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			size, env, arg = nil, nil, ""
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.size, size)
+			assert.Equal(t, c.env, env)
+			assert.Equal(t, c.arg, arg)
+		})
+	}
+
+	assert.Panics(t, func() { root.AddFlags(&Flag{Name: "pair", Arity: 2}) }, "Valueless flags can't take values.")
+	assert.Panics(t, func() { root.AddFlags(&Flag{Name: "pair", Arity: 2, Value: BoolVar(new(bool))}) }, "Boolean flags can't take values.")
+	assert.Panics(t, func() { root.AddFlags(&Flag{Name: "pair", Arity: 2, Value: IntVar(new(int))}) }, "Scalar flags can't hold several values.")
+}
+
+func TestParseImplicitValue(t *testing.T) {
+	var color string
+	var verbose bool
+	var arg string
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "color", Short: 'c', ImplicitValue: "always", Value: StringVar(&color)},
+		&Flag{Name: "verbose", Short: 'v', Value: BoolVar(&verbose)},
+	)
+	root.AddArgs(&Arg{Name: "arg", Value: StringVar(&arg)})
+
+	cases := map[string]struct {
+		args    []string
+		color   string
+		verbose bool
+		arg     string
+	}{
+		"Absent":        {args: []string{}},
+		"Implicit":      {args: []string{"--color"}, color: "always"},
+		"Assigned":      {args: []string{"--color=never"}, color: "never"},
+		"AssignedEmpty": {args: []string{"--color="}},
+		"NotConsumed":   {args: []string{"--color", "never"}, color: "always", arg: "never"},
+		"Short":         {args: []string{"-c"}, color: "always"},
+		"ShortAttached": {args: []string{"-cnever"}, color: "never"},
+		"ShortGrouped":  {args: []string{"-vc"}, color: "always", verbose: true},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			color, verbose, arg = "", false, ""
+			require.NoError(t, root.Parse(c.args))
+			assert.Equal(t, c.color, color)
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.arg, arg)
+		})
+	}
+}
+
+func TestParseDispatch(t *testing.T) {
+	newTree := func(rootAction Action) (*Command, *testAction) {
+		leafAction := &testAction{}
+		root := &Command{Name: "root", Action: rootAction}
+		group := &Command{Name: "group"}
+		hidden := &Command{Name: "hidden", Hidden: true}
+		root.AddCommands(group, hidden)
+		group.AddCommands(&Command{Name: "leaf", Action: leafAction.Invoke})
+		return root, leafAction
+	}
+
+	t.Run("RootWithoutAction", func(t *testing.T) {
+		root, _ := newTree(nil)
+		var err *MissingCommandError
+		require.True(t, errors.As(root.Parse(nil), &err))
+		assert.Equal(t, root, err.Command)
+		assert.EqualError(t, err, `missing command for "root"; available commands: group`)
+	})
+
+	t.Run("GroupWithoutAction", func(t *testing.T) {
+		rootAction := &testAction{}
+		root, _ := newTree(rootAction.Invoke)
+		err := root.Parse([]string{"group"})
+		assert.EqualError(t, err, `missing command for "root group"; available commands: leaf`)
+		assert.Nil(t, rootAction.Result)
+	})
+
+	t.Run("LeafUnderRootWithoutAction", func(t *testing.T) {
+		root, leafAction := newTree(nil)
+		require.NoError(t, root.Parse([]string{"group", "leaf"}))
+		require.NotNil(t, leafAction.Result)
+		assert.Equal(t, "root group leaf", leafAction.Result.FullName())
+	})
+
+	t.Run("LeafWithoutAction", func(t *testing.T) {
+		root, _ := newTree(nil)
+		assert.NoError(t, root.Parse([]string{"hidden"}))
+	})
+}
+
+func TestParseDefaultCommand(t *testing.T) {
+	var long bool
+	var preInvoked bool
+	listAction := &testAction{}
+	showAction := &testAction{}
+
+	root := &Command{Name: "root", DefaultCommand: "ls"}
+	list := &Command{
+		Name:      "list",
+		Aliases:   []string{"ls"},
+		Action:    listAction.Invoke,
+		PreAction: func(*Command) error { preInvoked = true; return nil },
+	}
+	show := &Command{Name: "show", Action: showAction.Invoke}
+	root.AddCommands(list, show)
+	list.AddFlags(&Flag{Name: "long", Value: WithDefault(BoolVar(&long), "true")})
+
+	t.Run("Default", func(t *testing.T) {
+		require.NoError(t, root.Parse(nil))
+		assert.Equal(t, list, listAction.Result)
+		assert.Nil(t, showAction.Result)
+		assert.True(t, preInvoked, "Default command's pre-action should be invoked.")
+		assert.True(t, long, "Default command's defaults should be applied.")
+	})
+
+	listAction.Reset()
+	long = false
+	t.Run("Explicit", func(t *testing.T) {
+		require.NoError(t, root.Parse([]string{"show"}))
+		assert.Nil(t, listAction.Result)
+		assert.Equal(t, show, showAction.Result)
+		assert.False(t, long)
+	})
+
+	listAction.Reset()
+	showAction.Reset()
+	t.Run("ImpliedByFlag", func(t *testing.T) {
+		require.NoError(t, root.Parse([]string{"--long=false"}))
+		assert.Equal(t, list, listAction.Result)
+		assert.False(t, long)
+
+		assert.EqualError(t, root.Parse([]string{"show", "--long"}), `unknown flag: long; --long is a flag of "root list"`)
+		assert.EqualError(t, root.Parse([]string{"--bogus"}), "unknown flag: bogus")
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		var x bool
+		leafAction := &testAction{}
+		root := &Command{Name: "root", DefaultCommand: "group"}
+		group := &Command{Name: "group", DefaultCommand: "leaf"}
+		leaf := &Command{Name: "leaf", Action: leafAction.Invoke}
+		root.AddCommands(group)
+		group.AddCommands(leaf)
+		leaf.AddFlags(&Flag{Name: "x", Short: 'x', Value: BoolVar(&x)})
+
+		require.NoError(t, root.Parse(nil))
+		assert.Equal(t, leaf, leafAction.Result)
+
+		leafAction.Reset()
+		require.NoError(t, root.Parse([]string{"-x"}))
+		assert.Equal(t, leaf, leafAction.Result)
+		assert.True(t, x)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		root := &Command{Name: "root"}
+		group := &Command{Name: "group", DefaultCommand: "missing"}
+		root.AddCommands(group, &Command{Name: "other"})
+		group.AddCommands(&Command{Name: "sub"})
+		assert.Panics(t, func() { root.Parse([]string{"other"}) }, "Defaults are checked even if their group isn't used.")
+		assert.Panics(t, func() { root.ParseKnown(nil) })
+	})
+}
+
 func TestParseNilValue(t *testing.T) {
 	command := &Command{}
 	command.AddFlags(&Flag{Name: "flag", Short: 'f'})
@@ -428,3 +1748,38 @@ func (a *testAction) Invoke(context *Command) error {
 	a.Result = context
 	return nil
 }
+
+func TestParseDefaultTraits(t *testing.T) {
+	var b bool
+	var strs []string
+
+	// Defaults don't change how their values are parsed. A boolean flag doesn't
+	// consume the next argument, and an aggregate arg accepts several.
+	root := &Command{Name: "app"}
+	root.AddFlags(&Flag{Name: "bool", Value: WithDefault(BoolVar(&b), "false")})
+	root.AddArgs(&Arg{Name: "strs", Value: WithDefault(StringsVar(&strs), "x", "y")})
+
+	require.NoError(t, root.Parse([]string{"--bool", "a", "b"}))
+	assert.True(t, b)
+	assert.Equal(t, []string{"a", "b"}, strs)
+
+	b, strs = true, nil
+	require.NoError(t, root.Parse(nil))
+	assert.False(t, b)
+	assert.Equal(t, []string{"x", "y"}, strs)
+}
+
+func TestParseWrappedDefaults(t *testing.T) {
+	var tags []string
+
+	// Defaults apply even when wrapped by another value.
+	root := &Command{Name: "app"}
+	root.AddFlags(&Flag{Name: "tags", Value: WithSeparator(WithDefault(StringsVar(&tags), "a", "b"), ",")})
+
+	require.NoError(t, root.Parse(nil))
+	assert.Equal(t, []string{"a", "b"}, tags)
+
+	tags = nil
+	require.NoError(t, root.Parse([]string{"--tags", "x,y"}))
+	assert.Equal(t, []string{"x", "y"}, tags)
+}
@@ -0,0 +1,79 @@
+package gargle
+
+// Dialect is a command-line syntax, which determines how arguments are split
+// into flags and values. Several dialects are provided by this package, and
+// others may be implemented. A dialect is selected by setting a root command's
+// Dialect.
+type Dialect interface {
+	// NewTokenizer returns a tokenizer for a list of arguments.
+	NewTokenizer(args []string) Tokenizer
+
+	// FlagPrefixes returns the prefixes of short and long flags, for display.
+	FlagPrefixes() (short, long string)
+
+	// ValueSeparator returns the separator between a flag and its value, for
+	// display.
+	ValueSeparator() string
+}
+
+var (
+	// GNUDialect is the default dialect. Long flags are prefixed with "--"
+	// and short flags with "-", e.g. "--name=value", "--name value", "-n value",
+	// or "-nvalue". Short flags may be grouped, e.g. "-abc" for "-a -b -c".
+	GNUDialect Dialect = gnuDialect{}
+
+	// GetoptDialect is like GNUDialect, but a short flag immediately followed
+	// by "=" is assigned the rest of its argument. For example, "-o=value"
+	// assigns "value" rather than "=value".
+	GetoptDialect Dialect = gnuDialect{assignShort: true}
+
+	// GoDialect matches the standard library's flag package. Long and short
+	// flags alike are prefixed with "-" or "--", e.g. "-name=value",
+	// "-name value", or "-n value". Flags can't be grouped.
+	GoDialect Dialect = prefixDialect{prefix: "-", separator: "=", flagType: TokenFlag, numbers: true}
+
+	// WindowsDialect uses Windows-style flags. Long and short flags alike are
+	// prefixed with "/" and separated from values with ":", e.g. "/name:value",
+	// "/name value", or "/n value". Flags can't be grouped.
+	WindowsDialect Dialect = prefixDialect{prefix: "/", separator: ":", flagType: TokenSlash}
+)
+
+// dialectOf returns the dialect of a command's tree.
+func dialectOf(command *Command) Dialect {
+	for command.Parent() != nil {
+		command = command.Parent()
+	}
+	if command.Dialect == nil {
+		return GNUDialect
+	}
+	return command.Dialect
+}
+
+type gnuDialect struct{ assignShort bool }
+
+func (d gnuDialect) FlagPrefixes() (string, string) { return "-", "--" }
+func (d gnuDialect) ValueSeparator() string         { return "=" }
+
+func (d gnuDialect) NewTokenizer(args []string) Tokenizer {
+	return &gnuTokenizer{args: args, assignShort: d.assignShort}
+}
+
+type prefixDialect struct {
+	prefix    string
+	separator string
+	flagType  TokenType
+	numbers   bool
+}
+
+func (d prefixDialect) FlagPrefixes() (string, string) { return d.prefix, d.prefix }
+func (d prefixDialect) ValueSeparator() string         { return d.separator }
+
+func (d prefixDialect) NewTokenizer(args []string) Tokenizer {
+	return &prefixTokenizer{
+		args:      args,
+		prefix:    d.prefix,
+		separator: d.separator,
+		flagType:  d.flagType,
+		numbers:   d.numbers,
+	}
+}
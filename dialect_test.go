@@ -0,0 +1,219 @@
+package gargle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialectTokenize(t *testing.T) {
+	cases := map[string]struct {
+		dialect  Dialect
+		args     []string
+		expected []Token
+	}{
+		"GNU": {
+			dialect: GNUDialect,
+			args:    []string{"--long=x", "-o=value", "-5"},
+			expected: []Token{
+				{TokenLong, "long"},
+				{TokenAssigned, "x"},
+				{TokenShort, "o"},
+				{TokenShort, "="},
+				{TokenShort, "v"},
+				{TokenShort, "a"},
+				{TokenShort, "l"},
+				{TokenShort, "u"},
+				{TokenShort, "e"},
+				{TokenNumber, "-5"},
+			},
+		},
+		"Getopt": {
+			dialect: GetoptDialect,
+			args:    []string{"-o=value", "-vo=", "-ab"},
+			expected: []Token{
+				{TokenShort, "o"},
+				{TokenAssigned, "value"},
+				{TokenShort, "v"},
+				{TokenShort, "o"},
+				{TokenAssigned, ""},
+				{TokenShort, "a"},
+				{TokenShort, "b"},
+			},
+		},
+		"Go": {
+			dialect: GoDialect,
+			args:    []string{"-name=value", "--long", "-v", "x", "-", "-5", "--", "-a"},
+			expected: []Token{
+				{TokenFlag, "name"},
+				{TokenAssigned, "value"},
+				{TokenFlag, "long"},
+				{TokenFlag, "v"},
+				{TokenValue, "x"},
+				{TokenValue, "-"},
+				{TokenNumber, "-5"},
+				{TokenVerbatim, "--"},
+				{TokenFlag, "a"},
+			},
+		},
+		"Windows": {
+			dialect: WindowsDialect,
+			args:    []string{"/out:file.txt", "/v", "-x", "/", "C:/path", "-5"},
+			expected: []Token{
+				{TokenSlash, "out"},
+				{TokenAssigned, "file.txt"},
+				{TokenSlash, "v"},
+				{TokenValue, "-x"},
+				{TokenValue, "/"},
+				{TokenValue, "C:/path"},
+				{TokenValue, "-5"},
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			tokenizer := c.dialect.NewTokenizer(c.args)
+			var actual []Token
+			for tok := tokenizer.Next(false); tok.Type != TokenEOF; tok = tokenizer.Next(false) {
+				actual = append(actual, tok)
+			}
+
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestDialectParse(t *testing.T) {
+	var verbose bool
+	var name string
+	var count int
+	var args []string
+
+	newCommand := func(dialect Dialect) *Command {
+		cmd := &Command{Name: "app", Dialect: dialect}
+		cmd.AddFlags(
+			&Flag{Name: "verbose", Short: 'v', Value: BoolVar(&verbose)},
+			&Flag{Name: "name", Value: StringVar(&name)},
+			&Flag{Name: "n", Value: IntVar(&count)},
+		)
+		cmd.AddArgs(&Arg{Name: "args", Value: StringsVar(&args)})
+		if dialect == WindowsDialect {
+			cmd.AddFlags(&Flag{Name: "required", Required: true})
+		}
+		return cmd
+	}
+
+	cases := map[string]struct {
+		dialect Dialect
+		args    []string
+		err     string
+		verbose bool
+		name    string
+		count   int
+		rest    []string
+	}{
+		"GetoptShortAssigned": {
+			dialect: GetoptDialect,
+			args:    []string{"-v=false", "--name=x"},
+			name:    "x",
+		},
+		"GoLongFlags": {
+			dialect: GoDialect,
+			args:    []string{"-verbose", "-name=x", "-n", "-3", "--name", "y", "a"},
+			verbose: true, name: "y", count: -3, rest: []string{"a"},
+		},
+		"GoShortFlag": {
+			dialect: GoDialect,
+			args:    []string{"-v", "-5"},
+			verbose: true, rest: []string{"-5"},
+		},
+		"GoUnknown": {
+			dialect: GoDialect,
+			args:    []string{"-nmae=x"},
+			err:     "unknown flag: nmae; did you mean -name?",
+		},
+		"GoMissingValue": {
+			dialect: GoDialect,
+			args:    []string{"-name"},
+			err:     "-name requires a value",
+		},
+		"Windows": {
+			dialect: WindowsDialect,
+			args:    []string{"/v", "/name:x", "/n", "2", "-v", "/name:", "/required"},
+			verbose: true, name: "", count: 2, rest: []string{"-v"},
+		},
+		"WindowsRequired": {
+			dialect: WindowsDialect,
+			args:    []string{"/v"},
+			err:     "missing required flag /required",
+		},
+		"WindowsInvalid": {
+			dialect: WindowsDialect,
+			args:    []string{"/n:x"},
+			err:     `invalid value "x" for /n: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+	}
+
+	for testName, c := range cases {
+		t.Run(testName, func(t *testing.T) {
+			verbose, name, count, args = false, "", 0, nil
+
+			err := newCommand(c.dialect).Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.verbose, verbose)
+			assert.Equal(t, c.name, name)
+			assert.Equal(t, c.count, count)
+			assert.Equal(t, c.rest, args)
+		})
+	}
+}
+
+// plusDialect is a minimal custom dialect where flags are prefixed with "+"
+// and never take joined values.
+type plusDialect struct{}
+
+func (plusDialect) NewTokenizer(args []string) Tokenizer { return &plusTokenizer{args} }
+func (plusDialect) FlagPrefixes() (string, string)       { return "+", "+" }
+func (plusDialect) ValueSeparator() string               { return " " }
+
+type plusTokenizer struct{ args []string }
+
+func (t *plusTokenizer) Peek() *Token { return nil }
+func (t *plusTokenizer) Next(verbatim bool) Token {
+	if len(t.args) == 0 {
+		return Token{Type: TokenEOF}
+	}
+	arg := t.args[0]
+	t.args = t.args[1:]
+	if !verbatim && len(arg) > 1 && arg[0] == '+' {
+		return Token{TokenFlag, arg[1:]}
+	}
+	return Token{TokenValue, arg}
+}
+
+func TestCustomDialect(t *testing.T) {
+	var verbose bool
+	var name string
+	var args []string
+
+	root := &Command{Name: "root", Dialect: plusDialect{}}
+	root.AddFlags(
+		&Flag{Name: "verbose", Short: 'v', Value: BoolVar(&verbose)},
+		&Flag{Name: "name", Value: StringVar(&name), Required: true},
+	)
+	root.AddArgs(&Arg{Name: "args", Value: StringsVar(&args)})
+
+	require.NoError(t, root.Parse([]string{"+v", "a", "+name", "x", "-b"}))
+	assert.True(t, verbose)
+	assert.Equal(t, "x", name)
+	assert.Equal(t, []string{"a", "-b"}, args)
+
+	assert.EqualError(t, root.Parse([]string{"+v"}), "missing required flag +name")
+}
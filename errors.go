@@ -0,0 +1,306 @@
+package gargle
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrHelp is returned from parsing when help was requested and written, such
+// as by a flag created with NewHelpFlag.
+var ErrHelp = errors.New("help requested")
+
+// ExitError is an error which carries a process exit status. Actions may return
+// it to control the status used by Main.
+type ExitError struct {
+	// Code is the process exit status.
+	Code int
+
+	// Err is an optional underlying error to report.
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// UnknownFlagError indicates a flag which isn't defined by the active command
+// or any of its parents.
+type UnknownFlagError struct {
+	// Name is the flag's name without its prefix, such as "verbose".
+	Name string
+
+	// Token is the flag as given, such as "--verbose" or "-v".
+	Token string
+
+	// Command is the active command when the flag was encountered.
+	Command *Command
+
+	// Candidates are flags the user may have meant, such as "--verbose".
+	Candidates []string
+
+	// Ambiguous is set when the flag is an abbreviation matching each of the
+	// candidates. Otherwise candidates are merely similar.
+	Ambiguous bool
+
+	// Owners are commands outside the active context which define the flag.
+	Owners []*Command
+}
+
+func (e *UnknownFlagError) Error() string {
+	if e.Ambiguous {
+		return "ambiguous flag: " + e.Name + "; could be " + orList(e.Candidates)
+	}
+
+	msg := "unknown flag: " + e.Name
+	if len(e.Candidates) != 0 {
+		msg += "; did you mean " + orList(e.Candidates) + "?"
+	}
+	if len(e.Owners) != 0 {
+		owners := make([]string, len(e.Owners))
+		for i, owner := range e.Owners {
+			owners[i] = fmt.Sprintf("%q", owner.FullName())
+		}
+		msg += "; " + e.Token + " is a flag of " + orList(owners)
+	}
+	return msg
+}
+
+// UnknownCommandError indicates a subcommand which isn't defined by the active
+// command.
+type UnknownCommandError struct {
+	// Name is the command as given.
+	Name string
+
+	// Command is the active command, i.e. the would-be parent.
+	Command *Command
+
+	// Candidates are subcommands the user may have meant.
+	Candidates []string
+
+	// Ambiguous is set when the name is an abbreviation matching each of the
+	// candidates. Otherwise candidates are merely similar.
+	Ambiguous bool
+}
+
+func (e *UnknownCommandError) Error() string {
+	fullName := e.Command.FullName() + " " + e.Name
+	if e.Ambiguous {
+		return fmt.Sprintf("%q is an ambiguous command; could be %s", fullName, orList(e.Candidates))
+	}
+
+	msg := fmt.Sprintf("%q is not a valid command", fullName)
+	if len(e.Candidates) != 0 {
+		msg += "; did you mean " + orList(e.Candidates) + "?"
+	}
+	return msg
+}
+
+// MissingCommandError indicates a command group was invoked without one of its
+// subcommands, and has no action of its own.
+type MissingCommandError struct {
+	// Command is the active command, i.e. the command group.
+	Command *Command
+}
+
+func (e *MissingCommandError) Error() string {
+	var names []string
+	for _, command := range e.Command.Commands() {
+		if !command.Hidden {
+			names = append(names, command.Name)
+		}
+	}
+	sort.Strings(names)
+
+	msg := fmt.Sprintf("missing command for %q", e.Command.FullName())
+	if len(names) != 0 {
+		msg += "; available commands: " + strings.Join(names, ", ")
+	}
+	return msg
+}
+
+// UnexpectedArgumentError indicates a positional argument which the active
+// command has no room for.
+type UnexpectedArgumentError struct {
+	// Value is the argument as given.
+	Value string
+
+	// Command is the active command when the argument was encountered.
+	Command *Command
+}
+
+func (e *UnexpectedArgumentError) Error() string {
+	return fmt.Sprintf("unexpected argument: %q", e.Value)
+}
+
+// MissingValueError indicates a flag which requires a value, but was given
+// none.
+type MissingValueError struct {
+	// Token is the flag as given, such as "--name" or "-n".
+	Token string
+
+	// Flag is the flag missing its value.
+	Flag *Flag
+
+	// Command is the active command when the flag was encountered.
+	Command *Command
+}
+
+func (e *MissingValueError) Error() string {
+	if e.Flag != nil && e.Flag.Arity > 1 {
+		return fmt.Sprintf("%s requires %d values", e.Token, e.Flag.Arity)
+	}
+	return e.Token + " requires a value"
+}
+
+// RepeatedFlagError indicates a flag with a non-aggregate value which was given
+// more than once, when its policy is RejectRepeats.
+type RepeatedFlagError struct {
+	// Token is the repeated flag as given, such as "--name" or "-n".
+	Token string
+
+	// Flag is the repeated flag.
+	Flag *Flag
+
+	// Command is the active command when the flag was repeated.
+	Command *Command
+}
+
+func (e *RepeatedFlagError) Error() string {
+	return e.Token + " may only be given once"
+}
+
+// InvalidValueError indicates a flag or argument value which was rejected.
+type InvalidValueError struct {
+	// Value is the rejected value as given.
+	Value string
+
+	// Token is the flag or argument as given, such as "--name" or "name".
+	Token string
+
+	// Flag is the flag the value was given for, if any.
+	Flag *Flag
+
+	// Arg is the positional argument the value was given for, if any.
+	Arg *Arg
+
+	// Command is the active command.
+	Command *Command
+
+	// Err is the reason the value was rejected.
+	Err error
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s: %s", e.Value, e.Token, e.Err.Error())
+}
+
+// Unwrap returns the reason the value was rejected.
+func (e *InvalidValueError) Unwrap() error { return e.Err }
+
+// MissingRequiredError indicates a required flag or argument which was not
+// given.
+type MissingRequiredError struct {
+	// Flag is the missing flag, if any.
+	Flag *Flag
+
+	// Arg is the missing positional argument, if any.
+	Arg *Arg
+
+	// Command is the active command.
+	Command *Command
+}
+
+func (e *MissingRequiredError) Error() string {
+	if e.Flag != nil {
+		return "missing required flag " + flagName(e.Flag, dialectOf(e.Command))
+	}
+	return "missing required argument " + e.Arg.Name
+}
+
+// ArgCountError indicates an aggregate positional argument which was given
+// fewer values than its minimum.
+type ArgCountError struct {
+	// Arg is the argument given too few values.
+	Arg *Arg
+
+	// Count is the number of values given.
+	Count int
+
+	// Command is the active command.
+	Command *Command
+}
+
+func (e *ArgCountError) Error() string {
+	return fmt.Sprintf("argument %s requires at least %d values; got %d", e.Arg.Name, e.Arg.minValues(), e.Count)
+}
+
+// ConstraintError indicates a combination of flags forbidden by a command's
+// constraints, such as those declared with ExactlyOneOf or Requires.
+type ConstraintError struct {
+	// Given are the flags which were given in violation of the constraint. If
+	// none are missing, these may not be used together.
+	Given []*Flag
+
+	// Missing are the flags required by the constraint, but not given. If none
+	// were given, at least one of these is required.
+	Missing []*Flag
+
+	// Command is the active command.
+	Command *Command
+}
+
+func (e *ConstraintError) Error() string {
+	names := func(flags []*Flag) []string {
+		s := make([]string, len(flags))
+		for i, flag := range flags {
+			s[i] = flagName(flag, dialectOf(e.Command))
+		}
+		return s
+	}
+
+	switch {
+	case len(e.Missing) == 0:
+		return andList(names(e.Given)) + " can't be used together"
+	case len(e.Given) == 1:
+		return names(e.Given)[0] + " requires " + andList(names(e.Missing))
+	case len(e.Given) != 0:
+		return andList(names(e.Given)) + " require " + andList(names(e.Missing))
+	case len(e.Missing) == 1:
+		return "missing required flag " + names(e.Missing)[0]
+	default:
+		return "one of " + orList(names(e.Missing)) + " is required"
+	}
+}
+
+// ValidationErrors is a collection of errors found while validating parsed
+// values. It's returned by commands which report all errors at once.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the collected errors, so errors.Is and errors.As match any of
+// them.
+func (e ValidationErrors) Unwrap() []error { return e }
+
+// flagName returns a flag's preferred display name, including its prefix.
+func flagName(flag *Flag, dialect Dialect) string {
+	short, long := dialect.FlagPrefixes()
+	if flag.Name != "" {
+		return long + flag.Name
+	}
+	return short + string(flag.Short)
+}
@@ -11,19 +11,41 @@ type Flag struct {
 	// independently as a short-form description.
 	Help string
 
-	// Placeholder is an optional override for the name of a flag's value.
+	// Placeholder is an optional override for the name of a flag's value. Flags
+	// with an arity above 1 should name each value, e.g. "W H".
 	Placeholder string
 
+	// Arity is the number of values consumed by each occurrence of the flag,
+	// such as 2 for "--resize W H". Values are set in order, so flags with an
+	// arity above 1 must have aggregate values. Zero is the same as 1.
+	Arity int
+
+	// ImplicitValue optionally makes a flag's value optional. When set, a flag
+	// given without an assigned value takes this value instead of consuming
+	// the next argument. For example, with an implicit value of "always",
+	// "--color" is equivalent to "--color=always". Short flags may have their
+	// value attached, e.g. "-cnever". Boolean flags implicitly use "true".
+	ImplicitValue string
+
 	// Short is an optional single-character short form for the flag.
 	// For example, 'h' would match the argument "-h".
 	Short rune
 
+	// NumberShorthand sets whether the flag may be given as a dash followed by
+	// a number, such as "-20" for "--lines=20". This applies only where the
+	// number doesn't name a short flag, and to one flag per command.
+	NumberShorthand bool
+
 	// Hidden sets whether the flag should be omitted from usage text.
 	Hidden bool
 
 	// Required sets the flag to generate an error when absent.
 	Required bool
 
+	// Repeats sets what happens when a flag with a non-aggregate value is
+	// given more than once. The default defers to the root command's policy.
+	Repeats RepeatPolicy
+
 	// PreAction is invoked after parsing, but before values are set. All pre-actions
 	// are executed unconditionally in the order encountered during parsing.
 	PreAction Action
@@ -45,3 +67,22 @@ func (f *Flag) setValue(s string) error {
 	}
 	return nil
 }
+
+// RepeatPolicy determines how a flag with a non-aggregate value is handled when
+// given more than once.
+type RepeatPolicy int
+
+const (
+	// DefaultRepeats defers to the root command's policy, or LastWins if it
+	// also has the default policy.
+	DefaultRepeats RepeatPolicy = iota
+
+	// LastWins sets the flag each time it's given, so the last value is kept.
+	LastWins
+
+	// FirstWins keeps the first value given and ignores the rest.
+	FirstWins
+
+	// RejectRepeats fails with a RepeatedFlagError.
+	RejectRepeats
+)
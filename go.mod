@@ -1,6 +1,13 @@
-module "github.com/ckarenz/gargle"
+module github.com/ckarenz/gargle
+
+go 1.20
+
+require (
+	github.com/ckarenz/wordwrap v1.0.0
+	github.com/stretchr/testify v1.2.1
+)
 
 require (
-	"github.com/ckarenz/wordwrap" v1.0.0
-	"github.com/stretchr/testify" v1.2.1
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
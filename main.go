@@ -0,0 +1,114 @@
+package gargle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Main parses the process's arguments with a root command and exits. Errors
+// are written to stderr, followed by a hint to show usage when the error was
+// caused by bad input.
+//
+// The exit status is 0 on success or when help is requested, 2 for invalid
+// usage, the code of an ExitError, or 1 for any other error.
+func Main(cmd *Command) {
+	os.Exit(run(cmd, os.Args[1:], os.Stderr))
+}
+
+// run parses arguments and reports any errors, returning an exit status.
+func run(cmd *Command, args []string, stderr io.Writer) int {
+	err := cmd.Parse(args)
+	if err == nil || errors.Is(err, ErrHelp) {
+		return 0
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Err != nil {
+			reportError(stderr, cmd, exitErr.Err)
+		}
+		return exitErr.Code
+	}
+
+	reportError(stderr, cmd, err)
+	if context := usageErrorContext(err); context != nil {
+		if hint := usageHint(context); hint != "" {
+			fmt.Fprintf(stderr, "Run '%s' for usage.\n", hint)
+		}
+		return 2
+	}
+	return 1
+}
+
+func reportError(w io.Writer, cmd *Command, err error) {
+	if cmd.Name == "" {
+		fmt.Fprintln(w, "error:", err)
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", cmd.Name, err)
+	}
+}
+
+// usageErrorContext returns the active command of an error caused by invalid
+// usage, or nil if the error isn't a usage error.
+func usageErrorContext(err error) *Command {
+	var (
+		unknownFlag    *UnknownFlagError
+		unknownCommand *UnknownCommandError
+		missingCommand *MissingCommandError
+		unexpectedArg  *UnexpectedArgumentError
+		missingValue   *MissingValueError
+		invalidValue   *InvalidValueError
+		missingReq     *MissingRequiredError
+		argCount       *ArgCountError
+		constraint     *ConstraintError
+		repeated       *RepeatedFlagError
+	)
+
+	switch {
+	case errors.As(err, &unknownFlag):
+		return unknownFlag.Command
+	case errors.As(err, &unknownCommand):
+		return unknownCommand.Command
+	case errors.As(err, &missingCommand):
+		return missingCommand.Command
+	case errors.As(err, &unexpectedArg):
+		return unexpectedArg.Command
+	case errors.As(err, &missingValue):
+		return missingValue.Command
+	case errors.As(err, &invalidValue):
+		return invalidValue.Command
+	case errors.As(err, &missingReq):
+		return missingReq.Command
+	case errors.As(err, &argCount):
+		return argCount.Command
+	case errors.As(err, &constraint):
+		return constraint.Command
+	case errors.As(err, &repeated):
+		return repeated.Command
+	}
+	return nil
+}
+
+// usageHint returns a command line which shows usage for a command, preferring
+// the nearest help command and falling back to a help flag. It returns an
+// empty string if there's no way to show help.
+func usageHint(context *Command) string {
+	path := ""
+	for c := context; c != nil; c = c.Parent() {
+		for _, sub := range c.Commands() {
+			if sub.Name == "help" {
+				return c.FullName() + " help" + path
+			}
+		}
+		path = " " + c.Name + path
+	}
+
+	for _, flag := range context.FullFlags() {
+		if flag.Name == "help" {
+			return context.FullName() + " --help"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package gargle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	newRoot := func() *Command {
+		root := &Command{Name: "app"}
+		sub := &Command{Name: "sub", Action: func(*Command) error { return errors.New("failed") }}
+		exit := &Command{Name: "exit", Action: func(*Command) error {
+			return &ExitError{Code: 3, Err: errors.New("custom")}
+		}}
+		quiet := &Command{Name: "quiet", Action: func(*Command) error { return &ExitError{Code: 4} }}
+		ok := &Command{Name: "ok", Action: func(*Command) error { return nil }}
+		docs := &Command{Name: "docs", Action: func(*Command) error { return fmt.Errorf("docs: %w", ErrHelp) }}
+		sub.AddFlags(&Flag{Name: "int", Repeats: RejectRepeats, Value: IntVar(new(int))})
+		pair := &Command{Name: "pair"}
+		pair.AddArgs(&Arg{Name: "values", Min: 2, Value: StringsVar(new([]string))})
+		json, yaml := &Flag{Name: "json"}, &Flag{Name: "yaml"}
+		ok.AddFlags(json, yaml)
+		ok.AtMostOneOf(json, yaml)
+		root.AddCommands(NewHelpCommand(func(*Command) error { return nil }), sub, exit, quiet, ok, pair, docs)
+		root.AddFlags(NewHelpFlag(func(*Command) error { return nil }))
+		return root
+	}
+
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"Success":   {args: []string{"ok"}},
+		"HelpFlag":  {args: []string{"--help"}},
+		"HelpCmd":   {args: []string{"help", "sub"}},
+		"HelpWrap":  {args: []string{"docs"}},
+		"ActionErr": {args: []string{"sub"}, code: 1, stderr: "app: failed\n"},
+		"ExitErr":   {args: []string{"exit"}, code: 3, stderr: "app: custom\n"},
+		"QuietExit": {args: []string{"quiet"}, code: 4},
+		"UsageErr": {
+			args:   []string{"sub", "--int=x"},
+			code:   2,
+			stderr: "app: invalid value \"x\" for --int: strconv.ParseInt: parsing \"x\": invalid syntax\nRun 'app help sub' for usage.\n",
+		},
+		"ArgCount": {
+			args:   []string{"pair", "x"},
+			code:   2,
+			stderr: "app: argument values requires at least 2 values; got 1\nRun 'app help pair' for usage.\n",
+		},
+		"Constraint": {
+			args:   []string{"ok", "--json", "--yaml"},
+			code:   2,
+			stderr: "app: --json and --yaml can't be used together\nRun 'app help ok' for usage.\n",
+		},
+		"Repeated": {
+			args:   []string{"sub", "--int=1", "--int=2"},
+			code:   2,
+			stderr: "app: --int may only be given once\nRun 'app help sub' for usage.\n",
+		},
+		"MissingCommand": {
+			args:   nil,
+			code:   2,
+			stderr: "app: missing command for \"app\"; available commands: docs, exit, help, ok, pair, quiet, sub\nRun 'app help' for usage.\n",
+		},
+		"RootUsageErr": {
+			args:   []string{"--nope"},
+			code:   2,
+			stderr: "app: unknown flag: nope\nRun 'app help' for usage.\n",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			b := &strings.Builder{}
+			assert.Equal(t, c.code, run(newRoot(), c.args, b))
+			assert.Equal(t, c.stderr, b.String())
+		})
+	}
+}
+
+func TestUsageHint(t *testing.T) {
+	root := &Command{Name: "app"}
+	group := &Command{Name: "group"}
+	leaf := &Command{Name: "leaf"}
+	root.AddCommands(group)
+	group.AddCommands(leaf)
+
+	assert.Equal(t, "", usageHint(leaf))
+
+	root.AddFlags(NewHelpFlag(nil))
+	assert.Equal(t, "app group leaf --help", usageHint(leaf))
+
+	root.AddCommands(NewHelpCommand(nil))
+	assert.Equal(t, "app help group leaf", usageHint(leaf))
+
+	group.AddCommands(NewHelpCommand(nil))
+	assert.Equal(t, "app group help leaf", usageHint(leaf))
+	assert.Equal(t, "app group help", usageHint(group))
+}
@@ -1,29 +1,52 @@
 package gargle
 
 import (
-	"fmt"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 // parser is a multi-phase command-line argument parser. Parsers are stateful
 // and should not be reused.
 type parser struct {
-	tokenizer *tokenizer
+	tokenizer Tokenizer
 	context   *Command
+	abbrev    bool
+	posix     bool // Whether to always stop parsing flags at the first argument.
+	repeats   RepeatPolicy
+	given     map[*Flag]bool
+
+	// When keepUnknown is set, unrecognized flags and arguments are collected
+	// in unknown rather than failing.
+	keepUnknown bool
+	unknown     []string
 
 	// Representations including all parseable entities.
 	commands   map[string]*Command
 	flags      map[string]*Flag
 	shortFlags map[string]*Flag
+	numberFlag *Flag
 	args       []*Arg
+
+	// Values given to the first of args so far, and values held back until
+	// all are known because an aggregate must share them with later args.
+	argCount int
+	pending  []string
 }
 
 // newParser creates a new parser with the a given command as its initial context.
 func newParser(rootCommand *Command, args []string) *parser {
 	p := &parser{
-		tokenizer:  newTokenizer(args),
+		tokenizer:  dialectOf(rootCommand).NewTokenizer(args),
+		abbrev:     rootCommand.AllowAbbreviations,
+		repeats:    rootCommand.Repeats,
+		given:      map[*Flag]bool{},
 		flags:      map[string]*Flag{},
 		shortFlags: map[string]*Flag{},
 	}
+	_, p.posix = os.LookupEnv("POSIXLY_CORRECT")
 	p.setContext(rootCommand)
 	return p
 }
@@ -38,7 +61,9 @@ func (p *parser) Context() *Command {
 func (p *parser) setContext(context *Command) {
 	p.commands = map[string]*Command{}
 	for _, command := range context.Commands() {
-		p.commands[command.Name] = command
+		for _, name := range command.Names() {
+			p.commands[name] = command
+		}
 	}
 
 	for _, flag := range context.Flags() {
@@ -48,6 +73,9 @@ func (p *parser) setContext(context *Command) {
 		if s := flag.Short; s != 0 {
 			p.shortFlags[string(s)] = flag
 		}
+		if flag.NumberShorthand {
+			p.numberFlag = flag
+		}
 	}
 
 	p.args = context.Args()
@@ -61,73 +89,394 @@ type entity struct {
 	Value  string
 }
 
+// passthrough is an entity for raw arguments following "--".
+type passthrough struct{ args *[]string }
+
+func (p passthrough) setValue(s string) error {
+	*p.args = append(*p.args, s)
+	return nil
+}
+
 func (p *parser) Parse() ([]entity, error) {
 	var parsed []entity
-	verbatim := false
+	verbatim := false // Set by "--"
+	argsOnly := false // Set by the first argument under strict ordering.
 	for {
-		switch token := p.tokenizer.Next(verbatim); token.Type {
-		case tokenEOF:
-			return parsed, nil
+		tok := p.tokenizer.Next(verbatim || argsOnly)
+		if argsOnly && !verbatim && tok.Type == TokenValue && tok.Value == "--" {
+			// Strict ordering stops flags, but "--" still ends the arguments.
+			tok.Type = TokenVerbatim
+		}
+		if tok.Type == TokenNumber {
+			tok = p.resolveNumber(tok)
+		}
 
-		case tokenVerbatim:
+		switch tok.Type {
+		case TokenEOF:
+			// Command groups may fall back to a default subcommand unless given
+			// positional arguments instead. Default names are checked before
+			// parsing, so the lookup always succeeds.
+			for p.context.DefaultCommand != "" && len(p.commands) != 0 {
+				name := p.context.DefaultCommand
+				command := p.commands[name]
+				parsed = append(parsed, entity{command, command.Name, name})
+				p.setContext(command)
+			}
+			return p.assignPending(parsed)
+
+		case TokenVerbatim:
 			verbatim = true
+			if p.keepUnknown && p.context.Passthrough == nil {
+				// Forward "--" along with everything after it, since it may
+				// change how the receiving program reads those arguments.
+				for ; tok.Type != TokenEOF; tok = p.tokenizer.Next(true) {
+					p.unknown = append(p.unknown, tok.Value)
+				}
+			}
 
-		case tokenLong:
-			flag, ok := p.flags[token.Value]
-			if !ok {
-				return parsed, fmt.Errorf("unknown flag: %s", token.Value)
+		case TokenLong, TokenShort, TokenFlag, TokenSlash:
+			flag, err := p.lookupFlagToken(&tok)
+			if err != nil {
+				// Flags of a default subcommand imply it.
+				if defaults := p.defaultsFor(tok); defaults != nil {
+					for _, command := range defaults {
+						parsed = append(parsed, entity{command, command.Name, command.Name})
+						p.setContext(command)
+					}
+					flag, err = p.lookupFlagToken(&tok)
+				}
+			}
+			if err != nil {
+				if p.skipUnknownFlag(tok, err) {
+					break
+				}
+				return parsed, err
 			}
 
-			value, err := p.parseFlagValue(flag, token)
+			value, err := p.parseFlagValue(flag, tok)
 			if err != nil {
 				return parsed, err
 			}
-			parsed = append(parsed, entity{flag, token.String(), value})
+			values := []string{value}
 
-		case tokenShort:
-			flag, ok := p.shortFlags[token.Value]
-			if !ok {
-				return parsed, fmt.Errorf("unknown flag: %s", token.Value)
+			// Flags with fixed arity consume the rest of their values in full.
+			for i := 1; i < flag.Arity; i++ {
+				value, err := p.nextFlagValue(flag, tok)
+				if err != nil {
+					return parsed, err
+				}
+				values = append(values, value)
 			}
 
-			value, err := p.parseFlagValue(flag, token)
-			if err != nil {
+			if skip, err := p.repeated(flag, tok.String()); err != nil {
+				return parsed, err
+			} else if skip {
+				break
+			}
+
+			for _, value := range values {
+				parsed = append(parsed, entity{flag, tok.String(), value})
+			}
+
+		case TokenNumber:
+			// Shorthand numbers are reported by their flag's name, and may
+			// repeat it, e.g. "-n 5 -20".
+			name := flagName(p.numberFlag, dialectOf(p.context))
+			if skip, err := p.repeated(p.numberFlag, name); err != nil {
 				return parsed, err
+			} else if skip {
+				break
+			}
+			parsed = append(parsed, entity{p.numberFlag, name, tok.Value[1:]})
+
+		case TokenValue:
+			if verbatim && p.context.Passthrough != nil {
+				parsed = append(parsed, entity{passthrough{p.context.Passthrough}, "--", tok.Value})
+				break
 			}
-			parsed = append(parsed, entity{flag, token.String(), value})
 
-		case tokenValue:
 			// Commands take precedence over positional arguments. Any remaining
-			// unparsed args are discarded for the next context.
+			// unparsed args are discarded for the next context. Commands which
+			// also accept arguments treat anything else as an argument, as does
+			// ParseKnown, which may have skipped an unknown flag's value.
 			if len(p.commands) != 0 {
-				command, ok := p.commands[token.Value]
-				if !ok {
-					fullName := p.context.FullName() + " " + token.Value
-					return parsed, fmt.Errorf("%q is not a valid command", fullName)
+				command, err := p.lookupCommand(tok)
+				if err == nil {
+					parsed = append(parsed, entity{command, command.Name, tok.Value})
+					p.setContext(command)
+					break
+				}
+				if len(p.args) == 0 && !p.keepUnknown {
+					return parsed, err
 				}
-				parsed = append(parsed, entity{command, command.Name, token.Value})
-				p.setContext(command)
-				break
 			}
 
 			if len(p.args) == 0 {
-				return parsed, fmt.Errorf("unexpected argument: %q", token.Value)
+				if p.keepUnknown {
+					p.unknown = append(p.unknown, tok.Value)
+					if p.posix || p.context.StrictOrdering {
+						p.commands = nil
+						argsOnly = true
+					}
+					break
+				}
+				return parsed, &UnexpectedArgumentError{Value: tok.Value, Command: p.context}
+			}
+
+			// Subcommands may not follow positional arguments, nor may flags
+			// under strict ordering.
+			p.commands = nil
+			if p.posix || p.context.StrictOrdering {
+				argsOnly = true
 			}
 
 			arg := p.args[0]
-			if !IsAggregate(arg.Value) {
+			if IsAggregate(arg.Value) && len(p.args) > 1 {
+				p.pending = append(p.pending, tok.Value)
+				break
+			}
+
+			p.argCount++
+			if limit := arg.maxValues(); limit != 0 && p.argCount == limit {
 				p.args = p.args[1:]
+				p.argCount = 0
+			}
+			parsed = append(parsed, entity{arg, arg.Name, tok.Value})
+		}
+	}
+}
+
+// assignPending distributes held values among the remaining args in order.
+// Each takes as many values as it can while leaving enough to satisfy the
+// minimums of those after it.
+func (p *parser) assignPending(parsed []entity) ([]entity, error) {
+	values := p.pending
+	for i, arg := range p.args {
+		reserved := 0
+		for _, next := range p.args[i+1:] {
+			reserved += next.minValues()
+		}
+
+		n := len(values) - reserved
+		if limit := arg.maxValues(); limit != 0 && n > limit {
+			n = limit
+		}
+		if n < 0 {
+			n = 0
+		}
+		for _, value := range values[:n] {
+			parsed = append(parsed, entity{arg, arg.Name, value})
+		}
+		values = values[n:]
+	}
+
+	if len(values) != 0 {
+		if !p.keepUnknown {
+			return parsed, &UnexpectedArgumentError{Value: values[0], Command: p.context}
+		}
+		p.unknown = append(p.unknown, values...)
+	}
+	return parsed, nil
+}
+
+// resolveNumber interprets a negative number token. Numbers may instead name a
+// group of short flags or be shorthand for a numeric flag. Otherwise they're
+// simply values.
+func (p *parser) resolveNumber(tok Token) Token {
+	r, _ := utf8.DecodeRuneInString(tok.Value[1:])
+	if t, ok := p.tokenizer.(numberTokenizer); ok {
+		if _, ok := p.shortFlags[string(r)]; ok {
+			return t.unnumber(tok)
+		}
+	}
+	if p.numberFlag != nil {
+		return tok
+	}
+	return Token{TokenValue, tok.Value}
+}
+
+// defaultsFor returns the chain of default subcommands leading to one which
+// defines a flag named by tok, or nil if there's none. Defaults are only
+// implied before any subcommand or positional argument is given.
+func (p *parser) defaultsFor(tok Token) []*Command {
+	if len(p.commands) == 0 {
+		return nil
+	}
+
+	var chain []*Command
+	for c := p.context; c.DefaultCommand != ""; {
+		c = c.lookupChild(c.DefaultCommand)
+		chain = append(chain, c)
+		for _, flag := range c.Flags() {
+			if flagMatches(flag, tok) {
+				return chain
 			}
-			parsed = append(parsed, entity{arg, arg.Name, token.Value})
 		}
 	}
+	return nil
+}
+
+// remainder consumes the unparsed remainder of a group of short flags, if the
+// dialect groups them and there is one.
+func (p *parser) remainder() string {
+	if t, ok := p.tokenizer.(groupingTokenizer); ok {
+		return t.remainder()
+	}
+	return ""
+}
+
+// repeated records a flag as given and applies its repeat policy. It returns
+// whether the flag's values should be skipped, or an error if the flag may
+// only be given once. Token is the flag as given, used in errors.
+func (p *parser) repeated(flag *Flag, token string) (skip bool, err error) {
+	if p.given[flag] && !IsAggregate(flag.Value) {
+		policy := flag.Repeats
+		if policy == DefaultRepeats {
+			policy = p.repeats
+		}
+		if policy == FirstWins {
+			return true, nil
+		}
+		if policy == RejectRepeats {
+			return false, &RepeatedFlagError{Token: token, Flag: flag, Command: p.context}
+		}
+	}
+	p.given[flag] = true
+	return false, nil
 }
 
-func (p *parser) parseFlagValue(flag *Flag, flagToken token) (string, error) {
-	if tok := p.tokenizer.Peek(); tok != nil && tok.Type == tokenAssigned {
+// skipUnknownFlag records an unknown flag as given, including any value joined
+// to it, if unknown flags are to be kept. It returns whether the flag was kept.
+func (p *parser) skipUnknownFlag(tok Token, err error) bool {
+	if unknown, ok := err.(*UnknownFlagError); !p.keepUnknown || !ok || unknown.Ambiguous {
+		return false
+	}
+
+	arg := tok.String()
+	if next := p.tokenizer.Peek(); next != nil && next.Type == TokenAssigned {
+		separator := "="
+		if tok.Type == TokenSlash {
+			separator = ":"
+		}
+		arg += separator + p.tokenizer.Next(true).Value
+	}
+
+	// There's no telling whether the rest of a short flag group is a value or
+	// more flags, so keep it together.
+	arg += p.remainder()
+
+	p.unknown = append(p.unknown, arg)
+	return true
+}
+
+// lookupFlagToken finds the flag named by any kind of flag token. Abbreviated
+// names are expanded in place so later errors show the name in full.
+func (p *parser) lookupFlagToken(tok *Token) (*Flag, error) {
+	switch tok.Type {
+	case TokenShort:
+		if flag, ok := p.shortFlags[tok.Value]; ok {
+			return flag, nil
+		}
+		return nil, p.unknownFlagError(*tok)
+
+	case TokenFlag, TokenSlash:
+		// These don't distinguish short flags from long ones, so try both.
+		if _, ok := p.flags[tok.Value]; !ok {
+			if flag, ok := p.shortFlags[tok.Value]; ok {
+				return flag, nil
+			}
+		}
+	}
+
+	flag, err := p.lookupFlag(*tok)
+	if err == nil {
+		tok.Value = flag.Name
+	}
+	return flag, err
+}
+
+// lookupFlag finds the long flag named by a token. If abbreviations are
+// allowed, the token may also name a unique prefix of a visible flag.
+func (p *parser) lookupFlag(tok Token) (*Flag, error) {
+	if flag, ok := p.flags[tok.Value]; ok {
+		return flag, nil
+	}
+
+	if p.abbrev && tok.Value != "" {
+		var match *Flag
+		var candidates []string
+		for name, flag := range p.flags {
+			if !flag.Hidden && strings.HasPrefix(name, tok.Value) {
+				match = flag
+				candidates = append(candidates, Token{tok.Type, name}.String())
+			}
+		}
+		switch len(candidates) {
+		case 0:
+		case 1:
+			return match, nil
+		default:
+			sort.Strings(candidates)
+			return nil, &UnknownFlagError{
+				Name:       tok.Value,
+				Token:      tok.String(),
+				Command:    p.context,
+				Candidates: candidates,
+				Ambiguous:  true,
+			}
+		}
+	}
+	return nil, p.unknownFlagError(tok)
+}
+
+// lookupCommand finds the subcommand named by a token. If abbreviations are
+// allowed, the token may also name a unique prefix of a visible command.
+func (p *parser) lookupCommand(tok Token) (*Command, error) {
+	if command, ok := p.commands[tok.Value]; ok {
+		return command, nil
+	}
+
+	if p.abbrev && tok.Value != "" {
+		// Several aliases may share a prefix without introducing ambiguity.
+		matches := map[*Command]bool{}
+		var match *Command
+		var candidates []string
+		for name, command := range p.commands {
+			if !command.Hidden && strings.HasPrefix(name, tok.Value) {
+				match = command
+				matches[command] = true
+				candidates = append(candidates, name)
+			}
+		}
+		switch len(matches) {
+		case 0:
+		case 1:
+			return match, nil
+		default:
+			sort.Strings(candidates)
+			return nil, &UnknownCommandError{
+				Name:       tok.Value,
+				Command:    p.context,
+				Candidates: candidates,
+				Ambiguous:  true,
+			}
+		}
+	}
+	return nil, p.unknownCommandError(tok)
+}
+
+func (p *parser) parseFlagValue(flag *Flag, flagToken Token) (string, error) {
+	if tok := p.tokenizer.Peek(); tok != nil && tok.Type == TokenAssigned {
 		if flag.Value == nil {
-			return "", fmt.Errorf("invalid value %q for %s: flag does not accept a value", tok.Value, flagToken)
+			return "", &InvalidValueError{
+				Value:   tok.Value,
+				Token:   flagToken.String(),
+				Flag:    flag,
+				Command: p.context,
+				Err:     errors.New("flag does not accept a value"),
+			}
 		}
+		p.tokenizer.Next(true)
 		return tok.Value, nil
 	}
 
@@ -141,9 +490,121 @@ func (p *parser) parseFlagValue(flag *Flag, flagToken token) (string, error) {
 		return "true", nil
 	}
 
+	// So are values with an implicit default, but they may still be attached
+	// to a short flag.
+	if flag.ImplicitValue != "" {
+		if remainder := p.remainder(); remainder != "" {
+			return remainder, nil
+		}
+		return flag.ImplicitValue, nil
+	}
+	return p.nextFlagValue(flag, flagToken)
+}
+
+// nextFlagValue consumes the next argument as a flag's value, whatever it is.
+func (p *parser) nextFlagValue(flag *Flag, flagToken Token) (string, error) {
 	tok := p.tokenizer.Next(true)
-	if tok.Type == tokenEOF {
-		return "", fmt.Errorf("%s requires a value", flagToken)
+	if tok.Type == TokenEOF {
+		return "", &MissingValueError{Token: flagToken.String(), Flag: flag, Command: p.context}
 	}
 	return tok.Value, nil
 }
+
+// unknownFlagError describes an unrecognized flag token, along with hints for
+// similarly named flags and other commands which accept the flag.
+func (p *parser) unknownFlagError(tok Token) error {
+	err := &UnknownFlagError{Name: tok.Value, Token: tok.String(), Command: p.context}
+	switch tok.Type {
+	case TokenLong:
+		var names []string
+		for name, flag := range p.flags {
+			if !flag.Hidden {
+				names = append(names, name)
+			}
+		}
+		for _, name := range suggest(tok.Value, names) {
+			err.Candidates = append(err.Candidates, "--"+name)
+		}
+
+		// Single-character long flags may have been intended as short flags.
+		if flag, ok := p.shortFlags[tok.Value]; ok && !flag.Hidden {
+			err.Candidates = append(err.Candidates, "-"+tok.Value)
+		}
+
+	case TokenShort:
+		// Short flags may have been intended as single-character long flags.
+		if flag, ok := p.flags[tok.Value]; ok && !flag.Hidden {
+			err.Candidates = append(err.Candidates, "--"+tok.Value)
+		}
+
+	case TokenFlag, TokenSlash:
+		var names []string
+		for name, flag := range p.flags {
+			if !flag.Hidden {
+				names = append(names, name)
+			}
+		}
+		for _, name := range suggest(tok.Value, names) {
+			err.Candidates = append(err.Candidates, Token{tok.Type, name}.String())
+		}
+	}
+
+	// Look for commands outside the active context which define the flag.
+	active := map[*Command]bool{}
+	root := p.context
+	for c := p.context; c != nil; c = c.Parent() {
+		active[c] = true
+		root = c
+	}
+
+	var walk func(c *Command)
+	walk = func(c *Command) {
+		if c.Hidden {
+			return
+		}
+		if !active[c] {
+			for _, flag := range c.Flags() {
+				if !flag.Hidden && flagMatches(flag, tok) {
+					err.Owners = append(err.Owners, c)
+					break
+				}
+			}
+		}
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+	return err
+}
+
+// unknownCommandError describes an unrecognized command token, along with
+// hints for similarly named commands.
+func (p *parser) unknownCommandError(tok Token) error {
+	var names []string
+	for _, command := range p.context.Commands() {
+		if !command.Hidden {
+			names = append(names, command.Names()...)
+		}
+	}
+
+	return &UnknownCommandError{
+		Name:       tok.Value,
+		Command:    p.context,
+		Candidates: suggest(tok.Value, names),
+	}
+}
+
+// flagMatches returns whether a flag is named by a long or short flag token.
+func flagMatches(flag *Flag, tok Token) bool {
+	switch tok.Type {
+	case TokenLong:
+		return flag.Name != "" && flag.Name == tok.Value
+	case TokenShort:
+		return flag.Short != 0 && string(flag.Short) == tok.Value
+	case TokenFlag, TokenSlash:
+		return flag.Name == tok.Value || flag.Short != 0 && string(flag.Short) == tok.Value
+	default:
+		return false
+	}
+}
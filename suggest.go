@@ -0,0 +1,97 @@
+package gargle
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// suggest returns the candidates most similar to s, ordered from closest to
+// furthest. Candidates which are too dissimilar to be plausible typos are
+// omitted, as are duplicates.
+func suggest(s string, candidates []string) []string {
+	// Allow roughly one edit for every three characters typed.
+	maxDistance := utf8.RuneCountInString(s) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	distances := map[string]int{}
+	var matches []string
+	for _, c := range candidates {
+		if _, ok := distances[c]; ok {
+			continue
+		}
+		if d := editDistance(s, c); d <= maxDistance {
+			distances[c] = d
+			matches = append(matches, c)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		di, dj := distances[matches[i]], distances[matches[j]]
+		if di != dj {
+			return di < dj
+		}
+		return matches[i] < matches[j]
+	})
+	return matches
+}
+
+// editDistance computes the optimal string alignment distance between two
+// strings. This is the Levenshtein distance extended to count transposition of
+// adjacent characters as a single edit, which is a common typo.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	// Keep the previous two rows of the distance matrix.
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(t)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// orList formats a list of strings as an English disjunction, e.g. "a, b, or c".
+func orList(items []string) string { return joinList(items, "or") }
+
+// andList formats a list of strings as an English conjunction, e.g. "a, b, and c".
+func andList(items []string) string { return joinList(items, "and") }
+
+func joinList(items []string, conjunction string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " " + conjunction + " " + items[1]
+	default:
+		return strings.Join(items[:len(items)-1], ", ") + ", " + conjunction + " " + items[len(items)-1]
+	}
+}
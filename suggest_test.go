@@ -0,0 +1,51 @@
+package gargle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"same", "same", 0},
+		{"verbose", "verbos", 1},
+		{"verbose", "vrebose", 1},
+		{"status", "stats", 1},
+		{"kitten", "sitting", 3},
+		{"héllo", "hello", 1},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, editDistance(c.a, c.b), "%q -> %q", c.a, c.b)
+		assert.Equal(t, c.expected, editDistance(c.b, c.a), "%q -> %q", c.b, c.a)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"verbose", "versions", "version", "push", "pull", "status", "push"}
+
+	cases := map[string]struct {
+		input    string
+		expected []string
+	}{
+		"NoMatch":     {"frobnicate", nil},
+		"Transposed":  {"verbsoe", []string{"verbose"}},
+		"Ordered":     {"versiom", []string{"version", "versions"}},
+		"Tied":        {"pulh", []string{"pull", "push"}},
+		"Deduplicate": {"puhs", []string{"push"}},
+		"ShortInput":  {"pul", []string{"pull"}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, suggest(c.input, candidates))
+		})
+	}
+}
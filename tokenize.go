@@ -1,54 +1,93 @@
 package gargle
 
 import (
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
 
-// tokenType enumerates the possible kinds of tokens.
-type tokenType int
+// TokenType enumerates the possible kinds of tokens.
+type TokenType int
 
 const (
-	tokenEOF tokenType = iota
+	TokenEOF TokenType = iota
 
-	tokenVerbatim // Verbatim symbol "--"
-	tokenLong     // Long flag with "--" prefix
-	tokenShort    // Short flag with "-" prefix
-	tokenValue    // Naked value
-	tokenAssigned // Value explicitly assigned to the previous flag.
+	TokenVerbatim // Verbatim symbol "--"
+	TokenLong     // Long flag with "--" prefix
+	TokenShort    // Short flag with "-" prefix
+	TokenFlag     // Long or short flag with "-" prefix, e.g. "-name" in Go style.
+	TokenSlash    // Long or short flag with "/" prefix, e.g. "/name" in Windows style.
+	TokenNumber   // Negative number, which may also be read as short flags.
+	TokenValue    // Naked value
+	TokenAssigned // Value explicitly assigned to the previous flag.
 
 	tokenRemainder // Remainder from previous short flag; never returned from Next().
 )
 
-// token represents a single parsed token.
-type token struct {
-	Type  tokenType
+// Token represents a single parsed token. Flag tokens hold the flag's name
+// without its prefix, and value tokens hold the value as given.
+type Token struct {
+	Type  TokenType
 	Value string
 }
 
-func (t token) String() string {
+// String returns the token as it would be written on the command line, e.g.
+// "--name" for a long flag.
+func (t Token) String() string {
 	switch t.Type {
-	case tokenLong:
+	case TokenLong:
 		return "--" + t.Value
-	case tokenShort:
+	case TokenShort, TokenFlag:
 		return "-" + t.Value
+	case TokenSlash:
+		return "/" + t.Value
 	default:
 		return t.Value
 	}
 }
 
-// tokenizer is a scanning tokenizer for command-line arguments.
-type tokenizer struct {
-	args []string
-	next *token // Next token on deck
+// Tokenizer is a scanning tokenizer for command-line arguments. Dialects create
+// a tokenizer for each parse. Flags are returned as TokenLong, TokenShort,
+// TokenFlag, or TokenSlash tokens. A value joined to a flag, as in
+// "--name=value", follows the flag as a TokenAssigned token. Short flag groups
+// and negative numbers which name short flags are only supported by the
+// package's own dialects.
+type Tokenizer interface {
+	// Peek returns the next token without consuming it, if there is one.
+	Peek() *Token
+
+	// Next returns the next token. If no tokens are available, it returns EOF.
+	// When verbatim is set the token, if any, will be returned as an argument.
+	Next(verbatim bool) Token
+}
+
+// groupingTokenizer is implemented by tokenizers for dialects where short flags
+// may be grouped, e.g. "-abc" for "-a -b -c".
+type groupingTokenizer interface {
+	// remainder consumes and returns the unparsed remainder of a group of
+	// short flags, if there is one.
+	remainder() string
 }
 
-func newTokenizer(args []string) *tokenizer {
-	return &tokenizer{args: args}
+// numberTokenizer is implemented by tokenizers which return TokenNumber for
+// negative numbers that may instead name a flag.
+type numberTokenizer interface {
+	// unnumber reinterprets a number token as a flag.
+	unnumber(tok Token) Token
+}
+
+// gnuTokenizer tokenizes GNU-style arguments, where long flags are prefixed
+// with "--" and short flags with "-". Short flags may be grouped.
+type gnuTokenizer struct {
+	args []string
+	next *Token // Next token on deck
+
+	// Whether "-o=value" assigns "value" to "-o", as opposed to "=value".
+	assignShort bool
 }
 
 // Peek returns the next token without consuming it, if there is one.
-func (t *tokenizer) Peek() *token {
+func (t *gnuTokenizer) Peek() *Token {
 	// Never return a remainder, because it's not a known tokon yet.
 	if t.next != nil && t.next.Type == tokenRemainder {
 		return nil
@@ -56,14 +95,30 @@ func (t *tokenizer) Peek() *token {
 	return t.next
 }
 
+// remainder consumes and returns the unparsed remainder of a group of short
+// flags, if there is one.
+func (t *gnuTokenizer) remainder() string {
+	if t.next == nil || t.next.Type != tokenRemainder {
+		return ""
+	}
+	remainder := t.next.Value
+	t.next = nil
+	return remainder
+}
+
+// unnumber reinterprets a number token as a group of short flags.
+func (t *gnuTokenizer) unnumber(tok Token) Token {
+	return t.decodeShort(tok.Value[1:])
+}
+
 // Next returns the next token. If no tokens are available, it returns EOF.
-// When forceVerbatim is set the token, if any, will be returned as an argument.
-func (t *tokenizer) Next(verbatim bool) token {
+// When verbatim is set the token, if any, will be returned as an argument.
+func (t *gnuTokenizer) Next(verbatim bool) Token {
 	if next := t.next; next != nil {
 		t.next = nil
 		if next.Type == tokenRemainder {
 			if verbatim {
-				return token{tokenAssigned, next.Value}
+				return Token{TokenAssigned, next.Value}
 			}
 			return t.decodeShort(next.Value)
 		}
@@ -71,43 +126,119 @@ func (t *tokenizer) Next(verbatim bool) token {
 	}
 
 	if len(t.args) == 0 {
-		return token{Type: tokenEOF}
+		return Token{Type: TokenEOF}
 	}
 	arg := t.args[0]
 	t.args = t.args[1:]
 	if verbatim {
-		return token{tokenValue, arg}
+		return Token{TokenValue, arg}
 	}
 
 	if strings.HasPrefix(arg, "--") {
 		// Special case: -- means return everything following verbatim.
 		if arg == "--" {
-			return token{tokenVerbatim, "--"}
+			return Token{TokenVerbatim, "--"}
 		}
 
 		parts := strings.SplitN(arg[2:], "=", 2)
 		if len(parts) > 1 {
-			t.next = &token{tokenAssigned, parts[1]}
+			t.next = &Token{TokenAssigned, parts[1]}
 		}
-		return token{tokenLong, parts[0]}
+		return Token{TokenLong, parts[0]}
 	}
 
 	if strings.HasPrefix(arg, "-") {
 		// Special case: - is often a placeholder for STDIN, and is a valid arg.
 		if arg == "-" {
-			return token{tokenValue, arg}
+			return Token{TokenValue, arg}
+		}
+		if isNumber(arg[1:]) {
+			return Token{TokenNumber, arg}
 		}
 		return t.decodeShort(arg[1:])
 	}
 
 	// Anything else is an argument.
-	return token{tokenValue, arg}
+	return Token{TokenValue, arg}
 }
 
-func (t *tokenizer) decodeShort(arg string) token {
+func (t *gnuTokenizer) decodeShort(arg string) Token {
 	flag, size := utf8.DecodeRuneInString(arg)
 	if remainder := arg[size:]; remainder != "" {
-		t.next = &token{tokenRemainder, arg[size:]}
+		if t.assignShort && remainder[0] == '=' {
+			t.next = &Token{TokenAssigned, remainder[1:]}
+		} else {
+			t.next = &Token{tokenRemainder, remainder}
+		}
+	}
+	return Token{TokenShort, string(flag)}
+}
+
+// prefixTokenizer tokenizes arguments in styles with a single flag prefix,
+// which doesn't distinguish long flags from short ones.
+type prefixTokenizer struct {
+	args []string
+	next *Token // Next token on deck
+
+	prefix    string    // Flag prefix, e.g. "-"
+	separator string    // Separator for flag values, e.g. "="
+	flagType  TokenType // Type of flag tokens
+	numbers   bool      // Whether to recognize negative numbers
+}
+
+// Peek returns the next token without consuming it, if there is one.
+func (t *prefixTokenizer) Peek() *Token { return t.next }
+
+// unnumber reinterprets a number token as a flag.
+func (t *prefixTokenizer) unnumber(tok Token) Token {
+	return Token{t.flagType, tok.Value[len(t.prefix):]}
+}
+
+// Next returns the next token. If no tokens are available, it returns EOF.
+// When verbatim is set the token, if any, will be returned as an argument.
+func (t *prefixTokenizer) Next(verbatim bool) Token {
+	if next := t.next; next != nil {
+		t.next = nil
+		return *next
+	}
+
+	if len(t.args) == 0 {
+		return Token{Type: TokenEOF}
+	}
+	arg := t.args[0]
+	t.args = t.args[1:]
+	if verbatim {
+		return Token{TokenValue, arg}
+	}
+
+	if arg == "--" {
+		return Token{TokenVerbatim, arg}
+	}
+	if arg == t.prefix || !strings.HasPrefix(arg, t.prefix) {
+		return Token{TokenValue, arg}
+	}
+	if t.numbers && isNumber(arg[len(t.prefix):]) {
+		return Token{TokenNumber, arg}
+	}
+
+	// Tolerate a doubled prefix, such as "--name" in Go style.
+	name := strings.TrimPrefix(arg[len(t.prefix):], t.prefix)
+	parts := strings.SplitN(name, t.separator, 2)
+	if len(parts) > 1 {
+		t.next = &Token{TokenAssigned, parts[1]}
+	}
+	return Token{t.flagType, parts[0]}
+}
+
+// isNumber returns whether a string looks like an unsigned number, such as "5",
+// "1.5e3", or "0x1F".
+func isNumber(s string) bool {
+	if s == "" || !(s[0] == '.' || '0' <= s[0] && s[0] <= '9') {
+		return false // Excludes words such as "inf" and "nan".
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
 	}
-	return token{tokenShort, string(flag)}
+	_, err := strconv.ParseUint(s, 0, 64)
+	return err == nil
 }
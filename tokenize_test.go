@@ -9,67 +9,81 @@ import (
 func TestTokenize(t *testing.T) {
 	cases := map[string]struct {
 		args     []string
-		expected []token
+		expected []Token
 	}{
 		"NoArgs": {},
 		"SingleLong": {
 			[]string{"--foo"},
-			[]token{{tokenLong, "foo"}},
+			[]Token{{TokenLong, "foo"}},
 		},
 		"MultipleLongs": {
 			[]string{"--one", "--two", "--", "--three"},
-			[]token{
-				{tokenLong, "one"},
-				{tokenLong, "two"},
-				{tokenVerbatim, "--"},
-				{tokenLong, "three"},
+			[]Token{
+				{TokenLong, "one"},
+				{TokenLong, "two"},
+				{TokenVerbatim, "--"},
+				{TokenLong, "three"},
 			},
 		},
 		"SingleShort": {
-			[]string{"-1"},
-			[]token{{tokenShort, "1"}},
+			[]string{"-a"},
+			[]Token{{TokenShort, "a"}},
+		},
+		"Numbers": {
+			[]string{"-1", "-1.5e3", "-.5", "-0x1F", "-1a", "-inf"},
+			[]Token{
+				{TokenNumber, "-1"},
+				{TokenNumber, "-1.5e3"},
+				{TokenNumber, "-.5"},
+				{TokenNumber, "-0x1F"},
+				{TokenShort, "1"},
+				{TokenShort, "a"},
+				{TokenShort, "i"},
+				{TokenShort, "n"},
+				{TokenShort, "f"},
+			},
 		},
 		"MultipleShort": {
 			[]string{"-ab", "-", "-c"},
-			[]token{
-				{tokenShort, "a"},
-				{tokenShort, "b"},
-				{tokenValue, "-"},
-				{tokenShort, "c"},
+			[]Token{
+				{TokenShort, "a"},
+				{TokenShort, "b"},
+				{TokenValue, "-"},
+				{TokenShort, "c"},
 			},
 		},
 		"SeparateValues": {
-			[]string{"--one", "arg1", "-2", "arg2"},
-			[]token{
-				{tokenLong, "one"},
-				{tokenValue, "arg1"},
-				{tokenShort, "2"},
-				{tokenValue, "arg2"},
+			[]string{"--one", "arg1", "-t", "arg2"},
+			[]Token{
+				{TokenLong, "one"},
+				{TokenValue, "arg1"},
+				{TokenShort, "t"},
+				{TokenValue, "arg2"},
 			},
 		},
 		"LongWithJoinedValue": {
 			[]string{"--one=arg", "--two=--"},
-			[]token{
-				{tokenLong, "one"},
-				{tokenAssigned, "arg"},
-				{tokenLong, "two"},
-				{tokenAssigned, "--"},
+			[]Token{
+				{TokenLong, "one"},
+				{TokenAssigned, "arg"},
+				{TokenLong, "two"},
+				{TokenAssigned, "--"},
 			},
 		},
 		"EmptyValue": {
 			[]string{"--flag="},
-			[]token{
-				{tokenLong, "flag"},
-				{tokenAssigned, ""},
+			[]Token{
+				{TokenLong, "flag"},
+				{TokenAssigned, ""},
 			},
 		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			tokenizer := newTokenizer(c.args)
-			var actual []token
-			for tok := tokenizer.Next(false); tok.Type != tokenEOF; tok = tokenizer.Next(false) {
+			tokenizer := GNUDialect.NewTokenizer(c.args)
+			var actual []Token
+			for tok := tokenizer.Next(false); tok.Type != TokenEOF; tok = tokenizer.Next(false) {
 				actual = append(actual, tok)
 			}
 
@@ -94,9 +108,9 @@ func TestTokenizeVerbatim(t *testing.T) {
 			expected: []string{"-1", "-", "-two"},
 		},
 		"SplitShort": {
-			args:      []string{"-123"},
+			args:      []string{"-abc"},
 			skipFirst: true,
-			expected:  []string{"23"},
+			expected:  []string{"bc"},
 		},
 		"SplitLong": {
 			args:      []string{"--one=arg"},
@@ -112,13 +126,13 @@ func TestTokenizeVerbatim(t *testing.T) {
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			tokenizer := newTokenizer(c.args)
+			tokenizer := GNUDialect.NewTokenizer(c.args)
 			if c.skipFirst {
 				tokenizer.Next(false)
 			}
 
 			var actual []string
-			for tok := tokenizer.Next(true); tok.Type != tokenEOF; tok = tokenizer.Next(true) {
+			for tok := tokenizer.Next(true); tok.Type != TokenEOF; tok = tokenizer.Next(true) {
 				actual = append(actual, tok.Value)
 			}
 
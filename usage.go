@@ -14,7 +14,8 @@ import (
 )
 
 // NewHelpFlag creates a standard help flag which invokes the an action when
-// parsed. This flag should be attached to the root command.
+// parsed. This flag should be attached to the root command. Parsing stops with
+// ErrHelp once help is written.
 func NewHelpFlag(writeHelp Action) *Flag {
 	if writeHelp == nil {
 		writeHelp = DefaultUsage()
@@ -24,16 +25,18 @@ func NewHelpFlag(writeHelp Action) *Flag {
 		Name: "help", Short: 'h',
 		Help: "Show usage",
 		PreAction: func(context *Command) error {
-			writeHelp(context)
-			os.Exit(0)
-			return nil
+			if err := writeHelp(context); err != nil {
+				return err
+			}
+			return ErrHelp
 		},
 	}
 }
 
 // NewHelpCommand creates a standard help command, which prints help for a
 // given subcommand. If no arguments are passed, it prints its parent's help.
-// This should be added to each command group.
+// This should be added to each command group. Parsing stops with ErrHelp once
+// help is written.
 func NewHelpCommand(writeHelp Action) *Command {
 	if writeHelp == nil {
 		writeHelp = DefaultUsage()
@@ -44,9 +47,10 @@ func NewHelpCommand(writeHelp Action) *Command {
 		Name: "help",
 		Help: "Show usage",
 		PreAction: func(context *Command) error {
-			writeCommandHelp(writeHelp, context.Parent(), *args)
-			os.Exit(0)
-			return nil
+			if err := writeCommandHelp(writeHelp, context.Parent(), *args); err != nil {
+				return err
+			}
+			return ErrHelp
 		},
 	}
 	cmd.AddArgs(&Arg{
@@ -61,12 +65,14 @@ func writeCommandHelp(writeHelp Action, context *Command, args []string) error {
 nextArg:
 	for _, arg := range args {
 		for _, cmd := range context.Commands() {
-			if cmd.Name == arg {
-				context = cmd
-				continue nextArg
+			for _, name := range cmd.Names() {
+				if name == arg {
+					context = cmd
+					continue nextArg
+				}
 			}
 		}
-		return fmt.Errorf("%q is not a valid command", context.FullName()+" "+arg)
+		return &UnknownCommandError{Name: arg, Command: context}
 	}
 	return writeHelp(context)
 }
@@ -124,33 +130,30 @@ func (u *UsageWriter) Format(command *Command) error {
 
 	args := command.Args() // These must be given in order, so don't sort them.
 
-	// Print the one-line usage summary. line: "some command [<command>] [<flags>] [<arg>...]"
-	fmt.Fprint(w, "Usage: ", command.FullName())
+	// Print the one-line usage summary. line: "some command [<flags>] [<command>]"
+	// or "some command [<flags>] [<arg>...]". Commands accepting either
+	// subcommands or arguments get a line for each.
+	synopsis := command.FullName()
 	if len(flags) != 0 {
-		fmt.Fprint(w, " [<flags>]")
+		synopsis += " [<flags>]"
 	}
+	synopsis += exclusiveSynopsis(command)
+	var lines []string
 	if len(subs) != 0 {
-		fmt.Fprint(w, " "+brackets("<command>", command.Action != nil))
-	} else if len(args) != 0 {
-		// Since positional args are ordered, everything prior to a required arg
-		// must also be required. Find the last one.
-		lastRequired := len(args)
-		for i := len(args) - 1; i >= 0; i-- {
-			if args[i].Required {
-				lastRequired = i
-				break
-			}
-		}
-
-		for i, arg := range args {
-			name := "<" + arg.Name + ">"
-			if IsAggregate(arg.Value) {
-				name += "..."
-			}
-			fmt.Fprint(w, " "+brackets(name, i > lastRequired))
+		optional := command.Action != nil || command.DefaultCommand != ""
+		lines = append(lines, synopsis+" "+brackets("<command>", optional))
+	}
+	if len(args) != 0 || command.Passthrough != nil {
+		line := synopsis + argsSynopsis(args)
+		if command.Passthrough != nil {
+			line += " [-- <args>...]"
 		}
+		lines = append(lines, line)
 	}
-	fmt.Fprintln(w)
+	if len(lines) == 0 {
+		lines = append(lines, synopsis)
+	}
+	fmt.Fprintln(w, "Usage: "+strings.Join(lines, "\n       "))
 
 	maxWidth := u.MaxLineWidth
 	if maxWidth == 0 {
@@ -168,22 +171,30 @@ func (u *UsageWriter) Format(command *Command) error {
 		fmt.Fprintln(w)
 	}
 
-	// Print commands/args first since we want them near the summary. We omit
-	// args if commands are present since we know they'll be ignored on parse.
+	// Print commands/args first since we want them near the summary.
 	if len(subs) != 0 {
 		fmt.Fprintln(w, "\nCommands:")
 		rows := make([][2]string, 0, len(subs))
 		for _, cmd := range subs {
 			// TODO: Should help be trimmed to the first line?
-			rows = append(rows, [2]string{u.Indent + cmd.Name, cmd.Help})
+			names := strings.Join(cmd.Names(), ", ")
+			if cmd == defaultCommand(command) {
+				names += " (default)"
+			}
+			rows = append(rows, [2]string{u.Indent + names, cmd.Help})
 		}
 		u.formatTwoColumns(w, rows, maxWidth)
-	} else if len(args) != 0 {
+	}
+	if len(args) != 0 {
 		fmt.Fprintln(w, "\nArguments:")
 		rows := make([][2]string, 0, len(args))
 		for _, arg := range args {
 			// TODO: Should help be trimmed to the first line?
-			rows = append(rows, [2]string{u.Indent + arg.Name, arg.Help})
+			help := arg.Help
+			if count := argCount(arg); count != "" {
+				help = strings.TrimSpace(help + " (" + count + ")")
+			}
+			rows = append(rows, [2]string{u.Indent + arg.Name, help})
 		}
 		u.formatTwoColumns(w, rows, maxWidth)
 	}
@@ -201,14 +212,15 @@ func (u *UsageWriter) Format(command *Command) error {
 		}
 
 		// Print each flag with short and long flags vertically aligned.
+		shortPrefix, longPrefix := dialectOf(command).FlagPrefixes()
 		rows := make([][2]string, 0, len(flags))
 		for _, flag := range flags {
 			var flagStr string
 			if haveShorts {
 				if flag.Short == rune(0) {
-					flagStr = "  "
+					flagStr = strings.Repeat(" ", len(shortPrefix)+1)
 				} else {
-					flagStr = "-" + string(flag.Short)
+					flagStr = shortPrefix + string(flag.Short)
 				}
 			}
 			if haveShorts && flag.Name != "" {
@@ -219,18 +231,33 @@ func (u *UsageWriter) Format(command *Command) error {
 				}
 			}
 			if flag.Name != "" {
-				flagStr += "--" + flag.Name
+				flagStr += longPrefix + flag.Name
 			}
 
 			// Now add the argument's placeholder if it has one.
 			if flag.Value != nil && !IsBoolean(flag.Value) {
-				flagStr += " "
-				if flag.Placeholder == "" {
-					flagStr += "VALUE"
-				} else {
-					flagStr += flag.Placeholder
+				placeholder := flag.Placeholder
+				if placeholder == "" {
+					placeholder = placeholderOf(flag.Value)
 				}
-				if IsAggregate(flag.Value) {
+				if placeholder == "" {
+					placeholder = "VALUE"
+					for i := 1; i < flag.Arity; i++ {
+						placeholder += " VALUE"
+					}
+				}
+				switch {
+				case flag.ImplicitValue == "":
+					flagStr += " " + placeholder
+				case flag.Name != "" || shortPrefix == longPrefix:
+					// Only grouped short flags take attached values.
+					flagStr += "[" + dialectOf(command).ValueSeparator() + placeholder + "]"
+				default:
+					flagStr += "[" + placeholder + "]"
+				}
+				// Multi-value flags are always aggregate, and a trailing
+				// ellipsis would read as repeating only the last value.
+				if IsAggregate(flag.Value) && flag.Arity <= 1 {
 					flagStr += "..."
 				}
 			}
@@ -246,6 +273,116 @@ func (u *UsageWriter) Format(command *Command) error {
 	return nil
 }
 
+// argsSynopsis formats positional arguments for a usage summary, e.g.
+// " <first> [<second>...]".
+func argsSynopsis(args []*Arg) string {
+	// Since positional args are ordered, everything prior to a required arg
+	// must also be required. Find the last one.
+	lastRequired := -1
+	for i := len(args) - 1; i >= 0; i-- {
+		if args[i].minValues() != 0 {
+			lastRequired = i
+			break
+		}
+	}
+
+	var s string
+	for i, arg := range args {
+		name := "<" + arg.Name + ">"
+		if IsAggregate(arg.Value) {
+			// Spell out values beyond the first which are also required.
+			for n := 1; n < arg.minValues(); n++ {
+				s += " " + name
+			}
+			name += "..."
+		}
+		s += " " + brackets(name, i > lastRequired)
+	}
+	return s
+}
+
+// exclusiveSynopsis formats groups of mutually exclusive flags which apply to
+// a command for a usage summary, e.g. " (--json | --yaml) [-q | -v]". Groups
+// requiring one of their flags are parenthesized.
+func exclusiveSynopsis(command *Command) string {
+	var stack []*Command
+	for c := command; c != nil; c = c.Parent() {
+		stack = append(stack, c)
+	}
+
+	dialect := dialectOf(command)
+	var s string
+	for i := len(stack) - 1; i >= 0; i-- {
+		for _, constraint := range stack[i].constraints {
+			if constraint.kind != exactlyOne && constraint.kind != atMostOne {
+				continue
+			}
+
+			var names []string
+			for _, flag := range constraint.flags {
+				if !flag.Hidden {
+					names = append(names, flagName(flag, dialect))
+				}
+			}
+			if len(names) == 0 {
+				continue
+			}
+
+			group := strings.Join(names, " | ")
+			if constraint.kind == exactlyOne {
+				s += " (" + group + ")"
+			} else {
+				s += " [" + group + "]"
+			}
+		}
+	}
+	return s
+}
+
+// argCount describes the number of values an aggregate argument accepts, if
+// it's more specific than "one or more", e.g. "2 to 4 values".
+func argCount(arg *Arg) string {
+	if !IsAggregate(arg.Value) {
+		return ""
+	}
+
+	min, max := arg.minValues(), arg.maxValues()
+	switch {
+	case max == 0 && min > 1:
+		return fmt.Sprintf("at least %d values", min)
+	case max == 0:
+		return ""
+	case min == max:
+		return "exactly " + pluralValues(max)
+	case min > 0:
+		return fmt.Sprintf("%d to %d values", min, max)
+	default:
+		return "at most " + pluralValues(max)
+	}
+}
+
+func pluralValues(n int) string {
+	if n == 1 {
+		return "1 value"
+	}
+	return fmt.Sprintf("%d values", n)
+}
+
+// defaultCommand returns a command's default subcommand, if any.
+func defaultCommand(command *Command) *Command {
+	if command.DefaultCommand == "" {
+		return nil
+	}
+	for _, cmd := range command.Commands() {
+		for _, name := range cmd.Names() {
+			if name == command.DefaultCommand {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
 func brackets(s string, optional bool) string {
 	if optional {
 		return "[" + s + "]"
@@ -10,7 +10,7 @@ import (
 
 func TestWriteCommandHelp(t *testing.T) {
 	root := &Command{Name: "root"}
-	sub1 := &Command{Name: "sub1"}
+	sub1 := &Command{Name: "sub1", Aliases: []string{"s1"}}
 	sub2 := &Command{Name: "sub2"}
 	root.AddCommands(sub1)
 	sub1.AddCommands(sub2)
@@ -25,6 +25,10 @@ func TestWriteCommandHelp(t *testing.T) {
 			args:     []string{"sub1"},
 			expected: "root sub1",
 		},
+		"ChildAlias": {
+			args:     []string{"s1", "sub2"},
+			expected: "root sub1 sub2",
+		},
 		"GrandChild": {
 			args:     []string{"sub1", "sub2"},
 			expected: "root sub1 sub2",
@@ -142,6 +146,269 @@ func TestUsageWriter(t *testing.T) {
 		assert.Equal(t, expected, b.String())
 	})
 
+	b.Reset()
+	t.Run("CommandAliases", func(t *testing.T) {
+		root := &Command{Name: "root"}
+		root.AddCommands(
+			&Command{Name: "remove", Aliases: []string{"rm"}, Help: "Remove things"},
+			&Command{Name: "list", Help: "List things"},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: root <command>",
+			"",
+			"Commands:",
+			"++list      ||List things",
+			"++remove, rm||Remove things",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(root))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("DefaultCommand", func(t *testing.T) {
+		root := &Command{Name: "root", DefaultCommand: "ls"}
+		root.AddCommands(
+			&Command{Name: "list", Aliases: []string{"ls"}, Help: "List things"},
+			&Command{Name: "show", Help: "Show a thing"},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: root [<command>]",
+			"",
+			"Commands:",
+			"++list, ls (default)||List things",
+			"++show              ||Show a thing",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(root))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("CommandsAndArgs", func(t *testing.T) {
+		cmd := &Command{Name: "checkout"}
+		cmd.AddCommands(&Command{Name: "status", Help: "Show status"})
+		cmd.AddArgs(&Arg{Name: "branch", Help: "Branch name", Required: true})
+
+		expected := strings.Join([]string{
+			"Usage: checkout <command>",
+			"       checkout <branch>",
+			"",
+			"Commands:",
+			"++status||Show status",
+			"",
+			"Arguments:",
+			"++branch||Branch name",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("Passthrough", func(t *testing.T) {
+		cmd := &Command{Name: "exec", Passthrough: new([]string)}
+		cmd.AddArgs(&Arg{Name: "name", Help: "Process name", Required: true})
+
+		expected := strings.Join([]string{
+			"Usage: exec <name> [-- <args>...]",
+			"",
+			"Arguments:",
+			"++name||Process name",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("Dialect", func(t *testing.T) {
+		root := &Command{Name: "root", Dialect: WindowsDialect}
+		sub := &Command{Name: "sub"}
+		root.AddCommands(sub)
+		sub.AddFlags(
+			&Flag{Name: "out", Short: 'o', Help: "Output file", Value: StringVar(new(string))},
+			&Flag{Name: "verbose", Short: 'v', Help: "Verbose output"},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: root sub [<flags>]",
+			"",
+			"Options:",
+			"++/o, /out VALUE||Output file",
+			"++/v, /verbose  ||Verbose output",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(sub))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("ImplicitValue", func(t *testing.T) {
+		cmd := &Command{Name: "command"}
+		cmd.AddFlags(
+			&Flag{Name: "color", Short: 'c', Placeholder: "WHEN", ImplicitValue: "always", Help: "Colorize output", Value: StringVar(new(string))},
+			&Flag{Short: 'l', Placeholder: "N", ImplicitValue: "1", Help: "Level", Value: IntVar(new(int))},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: command [<flags>]",
+			"",
+			"Options:",
+			"++-c, --color[=WHEN]||Colorize output",
+			"++-l[N]             ||Level",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("Arity", func(t *testing.T) {
+		cmd := &Command{Name: "command"}
+		cmd.AddFlags(
+			&Flag{Name: "resize", Placeholder: "W H", Arity: 2, Help: "Resize", Value: IntsVar(new([]int))},
+			&Flag{Name: "point", Arity: 3, Help: "Point", Value: StringsVar(new([]string))},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: command [<flags>]",
+			"",
+			"Options:",
+			"++--point VALUE VALUE VALUE||Point",
+			"++--resize W H             ||Resize",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("ArgCounts", func(t *testing.T) {
+		cmd := &Command{Name: "command"}
+		cmd.AddArgs(
+			&Arg{Name: "src", Help: "Sources", Min: 2, Value: StringsVar(new([]string))},
+			&Arg{Name: "dst", Help: "Destination", Required: true, Value: StringVar(new(string))},
+			&Arg{Name: "rest", Max: 3, Value: StringsVar(new([]string))},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: command <src> <src>... <dst> [<rest>...]",
+			"",
+			"Arguments:",
+			"++src ||Sources (at least 2 values)",
+			"++dst ||Destination",
+			"++rest||(at most 3 values)",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("OptionalArgs", func(t *testing.T) {
+		cmd := &Command{Name: "command"}
+		cmd.AddArgs(&Arg{Name: "arg1"}, &Arg{Name: "arg2", Value: StringsVar(new([]string))})
+
+		expected := strings.Join([]string{
+			"Usage: command [<arg1>] [<arg2>...]",
+			"",
+			"Arguments:",
+			"++arg1||",
+			"++arg2||",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("Maps", func(t *testing.T) {
+		cmd := &Command{Name: "command"}
+		cmd.AddFlags(
+			&Flag{Name: "env", Help: "Environment", Value: StringMapVar(new(map[string]string))},
+			&Flag{Name: "label", Help: "Labels", Value: WithDefault(StringMapVar(new(map[string]string)), "a=b")},
+			&Flag{Name: "size", Help: "Sizes", Value: WithSeparator(IntMapVar(new(map[string]int)), ",")},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: command [<flags>]",
+			"",
+			"Options:",
+			"++--env KEY=VALUE...  ||Environment",
+			"++--label KEY=VALUE...||Labels",
+			"++--size KEY=VALUE... ||Sizes",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("Enums", func(t *testing.T) {
+		cmd := &Command{Name: "command"}
+		cmd.AddFlags(
+			&Flag{Name: "format", Help: "Output format", Value: EnumVar(new(string), "json", "yaml", "table")},
+			&Flag{Name: "level", Placeholder: "LEVEL", Help: "Log level", Value: EnumVar(new(string), "debug", "info")},
+		)
+
+		expected := strings.Join([]string{
+			"Usage: command [<flags>]",
+			"",
+			"Options:",
+			"++--format json|yaml|table||Output format",
+			"++--level LEVEL           ||Log level",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(cmd))
+		assert.Equal(t, expected, b.String())
+	})
+
+	b.Reset()
+	t.Run("ExclusiveFlags", func(t *testing.T) {
+		root := &Command{Name: "root"}
+		quiet := &Flag{Name: "quiet", Help: "Quiet output"}
+		verbose := &Flag{Name: "verbose", Help: "Verbose output"}
+		root.AddFlags(quiet, verbose)
+		root.AtMostOneOf(quiet, verbose)
+
+		sub := &Command{Name: "sub"}
+		json := &Flag{Name: "json", Help: "JSON output"}
+		yaml := &Flag{Name: "yaml", Help: "YAML output"}
+		hidden := &Flag{Name: "xml", Hidden: true}
+		sub.AddFlags(json, yaml, hidden)
+		sub.ExactlyOneOf(json, yaml, hidden)
+		sub.RequiredTogether(json, yaml)
+		root.AddCommands(sub)
+
+		expected := strings.Join([]string{
+			"Usage: root sub [<flags>] [--quiet | --verbose] (--json | --yaml)",
+			"",
+			"Options:",
+			"++--json   ||JSON output",
+			"++--quiet  ||Quiet output",
+			"++--verbose||Verbose output",
+			"++--yaml   ||YAML output",
+			"",
+		}, "\n")
+
+		assert.NoError(t, writer.Format(sub))
+		assert.Equal(t, expected, b.String())
+	})
+
 	b.Reset()
 	t.Run("RequiredArgs", func(t *testing.T) {
 		cmd := &Command{Name: "command"}
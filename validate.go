@@ -0,0 +1,145 @@
+package gargle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Validator checks a string after it's been successfully set on a value. A
+// returned error rejects the value.
+type Validator func(s string) error
+
+type validatedValue struct {
+	value      Value
+	validators []Validator
+}
+
+// WithValidation wraps a value with validators which are run in order each
+// time the value is set. Aggregate values validate each element separately,
+// including those split from a single argument by WithSeparator.
+func WithValidation(v Value, validators ...Validator) Value {
+	return validatedValue{v, validators}
+}
+
+func (v validatedValue) IsBoolean() bool     { return IsBoolean(v.value) }
+func (v validatedValue) IsAggregate() bool   { return IsAggregate(v.value) }
+func (v validatedValue) placeholder() string { return placeholderOf(v.value) }
+func (v validatedValue) String() string      { return v.value.String() }
+func (v validatedValue) unwrap() Value       { return v.value }
+func (v validatedValue) Set(s string) error {
+	// Validate each element rather than the argument they were split from.
+	if sep, ok := v.value.(separatedValue); ok {
+		for _, part := range splitEscaped(s, sep.separator) {
+			if err := (validatedValue{sep.value, v.validators}).Set(part); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := v.value.Set(s); err != nil {
+		return err
+	}
+	for _, validate := range v.validators {
+		if err := validate(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InRange requires a number between min and max, inclusive. Integers may be
+// given in any base accepted by IntVar.
+func InRange(min, max float64) Validator {
+	return func(s string) error {
+		n, err := parseNumber(s)
+		if err != nil {
+			return err
+		}
+		if n < min || n > max {
+			return fmt.Errorf("must be between %g and %g", min, max)
+		}
+		return nil
+	}
+}
+
+// Length requires a string with a number of characters between min and max,
+// inclusive. A max of zero is unbounded.
+func Length(min, max int) Validator {
+	return func(s string) error {
+		n := utf8.RuneCountInString(s)
+		switch {
+		case max == 0 && n < min:
+			return fmt.Errorf("must be at least %d characters", min)
+		case max != 0 && (n < min || n > max):
+			return fmt.Errorf("must be between %d and %d characters", min, max)
+		default:
+			return nil
+		}
+	}
+}
+
+// MatchRegexp requires a string matching a regular expression. Anchor the
+// expression to match the entire string.
+func MatchRegexp(re *regexp.Regexp) Validator {
+	return func(s string) error {
+		if !re.MatchString(s) {
+			return fmt.Errorf("must match %s", re)
+		}
+		return nil
+	}
+}
+
+// NonEmpty requires a non-empty string.
+func NonEmpty() Validator {
+	return func(s string) error {
+		if s == "" {
+			return errors.New("must not be empty")
+		}
+		return nil
+	}
+}
+
+// FileExists requires the path of an existing file which isn't a directory.
+func FileExists() Validator {
+	return func(s string) error {
+		info, err := os.Stat(s)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return errors.New("is a directory")
+		}
+		return nil
+	}
+}
+
+// DirExists requires the path of an existing directory.
+func DirExists() Validator {
+	return func(s string) error {
+		info, err := os.Stat(s)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return errors.New("is not a directory")
+		}
+		return nil
+	}
+}
+
+// parseNumber parses an integer or floating point number.
+func parseNumber(s string) (float64, error) {
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return float64(n), nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,126 @@
+package gargle
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidators(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	missing := filepath.Join(dir, "missing")
+
+	cases := map[string]struct {
+		validator Validator
+		s         string
+		err       string
+	}{
+		"InRange":          {validator: InRange(1, 10), s: "10"},
+		"InRangeHex":       {validator: InRange(1, 10), s: "0x0a"},
+		"InRangeFloat":     {validator: InRange(0, 1), s: "0.5"},
+		"BelowRange":       {validator: InRange(1, 10), s: "0", err: "must be between 1 and 10"},
+		"AboveRange":       {validator: InRange(0, 1), s: "1.5", err: "must be between 0 and 1"},
+		"RangeNotNumber":   {validator: InRange(0, 1), s: "x", err: `"x" is not a number`},
+		"Length":           {validator: Length(2, 3), s: "héy"},
+		"TooShort":         {validator: Length(2, 3), s: "h", err: "must be between 2 and 3 characters"},
+		"TooLong":          {validator: Length(2, 3), s: "héyy", err: "must be between 2 and 3 characters"},
+		"Unbounded":        {validator: Length(2, 0), s: "hello"},
+		"UnboundedShort":   {validator: Length(2, 0), s: "h", err: "must be at least 2 characters"},
+		"Regexp":           {validator: MatchRegexp(regexp.MustCompile(`^[a-z]+$`)), s: "abc"},
+		"RegexpMismatch":   {validator: MatchRegexp(regexp.MustCompile(`^[a-z]+$`)), s: "ab1", err: "must match ^[a-z]+$"},
+		"NonEmpty":         {validator: NonEmpty(), s: " "},
+		"Empty":            {validator: NonEmpty(), s: "", err: "must not be empty"},
+		"FileExists":       {validator: FileExists(), s: file},
+		"FileIsDir":        {validator: FileExists(), s: dir, err: "is a directory"},
+		"FileMissing":      {validator: FileExists(), s: missing, err: "stat " + missing + ": no such file or directory"},
+		"DirExists":        {validator: DirExists(), s: dir},
+		"DirIsFile":        {validator: DirExists(), s: file, err: "is not a directory"},
+		"DirMissingParent": {validator: DirExists(), s: filepath.Join(missing, "x"), err: "stat " + filepath.Join(missing, "x") + ": no such file or directory"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := c.validator(c.s)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseValidation(t *testing.T) {
+	var port int
+	var names []string
+	var verbose bool
+
+	root := &Command{Name: "app"}
+	root.AddFlags(
+		&Flag{Name: "port", Value: WithValidation(IntVar(&port), InRange(1, 65535))},
+		&Flag{Name: "verbose", Value: WithValidation(BoolVar(&verbose))},
+	)
+	root.AddArgs(&Arg{Name: "names", Value: WithSeparator(WithValidation(StringsVar(&names), NonEmpty(), Length(0, 4)), ",")})
+
+	cases := map[string]struct {
+		args    []string
+		err     string
+		port    int
+		names   []string
+		verbose bool
+	}{
+		"Valid":         {args: []string{"--port", "80", "--verbose", "a,b", "c"}, port: 80, names: []string{"a", "b", "c"}, verbose: true},
+		"InvalidFlag":   {args: []string{"--port", "0"}, err: `invalid value "0" for --port: must be between 1 and 65535`},
+		"InvalidType":   {args: []string{"--port", "x"}, err: `invalid value "x" for --port: strconv.ParseInt: parsing "x": invalid syntax`},
+		"InvalidArg":    {args: []string{"a,"}, err: `invalid value "a," for names: must not be empty`},
+		"InvalidLength": {args: []string{"abcdef"}, err: `invalid value "abcdef" for names: must be between 0 and 4 characters`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			port, names, verbose = 0, nil, false
+
+			err := root.Parse(c.args)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.port, port)
+			assert.Equal(t, c.names, names)
+			assert.Equal(t, c.verbose, verbose)
+		})
+	}
+}
+
+func TestParseValidatedSeparator(t *testing.T) {
+	var codes []string
+
+	root := &Command{Name: "app"}
+	root.AddFlags(&Flag{Name: "code", Value: WithValidation(WithSeparator(StringsVar(&codes), ","), Length(0, 2))})
+
+	require.NoError(t, root.Parse([]string{"--code", "ab,cd", "--code=e"}))
+	assert.Equal(t, []string{"ab", "cd", "e"}, codes)
+
+	codes = nil
+	assert.EqualError(t, root.Parse([]string{"--code", "ab,cde"}), `invalid value "ab,cde" for --code: must be between 0 and 2 characters`)
+}
+
+func TestParseValidatedDefault(t *testing.T) {
+	var s string
+
+	root := &Command{Name: "app"}
+	root.AddFlags(&Flag{Name: "name", Value: WithValidation(WithDefault(StringVar(&s), "dflt"), NonEmpty())})
+
+	require.NoError(t, root.Parse(nil))
+	assert.Equal(t, "dflt", s)
+
+	assert.EqualError(t, root.Parse([]string{"--name="}), `invalid value "" for --name: must not be empty`)
+}
@@ -1,5 +1,7 @@
 package gargle
 
+import "strings"
+
 // Value is an interface implemented by all value types.
 type Value interface {
 	String() string
@@ -33,6 +35,42 @@ func IsAggregate(v Value) bool {
 	return ok && agg.IsAggregate()
 }
 
+// placeholderOf returns a value's preferred placeholder in usage, if any.
+func placeholderOf(v Value) string {
+	type placeholderValue interface{ placeholder() string }
+
+	if p, ok := v.(placeholderValue); ok {
+		return p.placeholder()
+	}
+	return ""
+}
+
+// wrappedValue is implemented by values which wrap another, such as those
+// returned by WithDefault, WithSeparator, and WithValidation.
+type wrappedValue interface {
+	unwrap() Value
+}
+
+// resettableValue is implemented by values which track state during a single
+// parse, such as the keys given to a map.
+type resettableValue interface {
+	reset()
+}
+
+// resetValue clears any per-parse state of a value and those it wraps.
+func resetValue(v Value) {
+	for v != nil {
+		if r, ok := v.(resettableValue); ok {
+			r.reset()
+		}
+		w, ok := v.(wrappedValue)
+		if !ok {
+			return
+		}
+		v = w.unwrap()
+	}
+}
+
 type defaultValue struct {
 	value    Value
 	defaults []string
@@ -47,16 +85,85 @@ func WithDefault(v Value, s ...string) Value {
 	return defaultValue{v, s}
 }
 
-func (v defaultValue) String() string     { return v.value.String() }
-func (v defaultValue) Set(s string) error { return v.value.Set(s) }
+func (v defaultValue) IsBoolean() bool     { return IsBoolean(v.value) }
+func (v defaultValue) IsAggregate() bool   { return IsAggregate(v.value) }
+func (v defaultValue) placeholder() string { return placeholderOf(v.value) }
+func (v defaultValue) String() string      { return v.value.String() }
+func (v defaultValue) Set(s string) error  { return v.value.Set(s) }
+func (v defaultValue) unwrap() Value       { return v.value }
+
+// applyDefault sets a value's defaults, if any, even if wrapped by another.
 func applyDefault(v Value) error {
-	def, ok := v.(defaultValue)
-	if ok {
-		for _, d := range def.defaults {
-			if err := def.value.Set(d); err != nil {
-				return err
+	for {
+		switch w := v.(type) {
+		case defaultValue:
+			resetValue(w.value)
+			for _, d := range w.defaults {
+				if err := w.value.Set(d); err != nil {
+					return err
+				}
 			}
+			return nil
+		case wrappedValue:
+			v = w.unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+type separatedValue struct {
+	value     Value
+	separator string
+}
+
+// WithSeparator wraps an aggregate value so each argument may hold several
+// values divided by a separator, e.g. "a,b,c" with a separator of ",". A
+// separator preceded by a backslash is taken literally, as is a doubled
+// backslash.
+func WithSeparator(v Value, separator string) Value {
+	if !IsAggregate(v) {
+		panic("only aggregate values may have separators")
+	}
+	if separator == "" {
+		panic("separators may not be empty")
+	}
+	return separatedValue{v, separator}
+}
+
+func (v separatedValue) IsAggregate() bool   { return true }
+func (v separatedValue) placeholder() string { return placeholderOf(v.value) }
+func (v separatedValue) String() string      { return v.value.String() }
+func (v separatedValue) unwrap() Value       { return v.value }
+func (v separatedValue) Set(s string) error {
+	for _, part := range splitEscaped(s, v.separator) {
+		if err := v.value.Set(part); err != nil {
+			return err
 		}
 	}
 	return nil
 }
+
+// splitEscaped splits a string around each unescaped instance of sep.
+func splitEscaped(s, sep string) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], `\`+sep):
+			b.WriteString(sep)
+			i += 1 + len(sep)
+		case strings.HasPrefix(s[i:], `\\`):
+			b.WriteByte('\\')
+			i += 2
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, b.String())
+			b.Reset()
+			i += len(sep)
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return append(parts, b.String())
+}
@@ -1,8 +1,12 @@
 package gargle
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -37,6 +41,22 @@ func (v *negatedValue) Set(s string) error {
 	return err
 }
 
+type boolSliceValue []bool
+
+// BoolsVar wraps a slice of booleans. Unlike BoolVar, each value must be given
+// explicitly.
+func BoolsVar(v *[]bool) Value { return (*boolSliceValue)(v) }
+
+func (v *boolSliceValue) IsAggregate() bool { return true }
+func (v *boolSliceValue) String() string    { return fmt.Sprintf("%v", *v) }
+func (v *boolSliceValue) Set(s string) error {
+	val, err := strconv.ParseBool(s)
+	if err == nil {
+		*v = append(*v, val)
+	}
+	return err
+}
+
 type stringValue string
 
 // StringVar wraps a string.
@@ -48,6 +68,48 @@ func (v *stringValue) Set(s string) error {
 	return nil
 }
 
+type enumValue[T ~string] struct {
+	value   *T
+	choices []T
+}
+
+// EnumVar wraps a string which must be one of a fixed set of choices.
+func EnumVar(v *string, choices ...string) Value { return TypedEnumVar(v, choices...) }
+
+// TypedEnumVar is like EnumVar for any string type, such as a named type with
+// constants for each choice.
+func TypedEnumVar[T ~string](v *T, choices ...T) Value {
+	if len(choices) == 0 {
+		panic("enums must have at least one choice")
+	}
+	return &enumValue[T]{v, choices}
+}
+
+func (v *enumValue[T]) placeholder() string { return strings.Join(v.names(), "|") }
+func (v *enumValue[T]) String() string      { return string(*v.value) }
+func (v *enumValue[T]) Set(s string) error {
+	for _, choice := range v.choices {
+		if string(choice) == s {
+			*v.value = choice
+			return nil
+		}
+	}
+
+	msg := "must be " + orList(v.names())
+	if candidates := suggest(s, v.names()); len(candidates) != 0 {
+		msg += "; did you mean " + candidates[0] + "?"
+	}
+	return errors.New(msg)
+}
+
+func (v *enumValue[T]) names() []string {
+	names := make([]string, len(v.choices))
+	for i, choice := range v.choices {
+		names[i] = string(choice)
+	}
+	return names
+}
+
 type stringSliceValue []string
 
 // StringsVar wraps a slice of strings.
@@ -86,6 +148,35 @@ func (v *intSliceValue) Set(s string) error {
 	if err == nil {
 		*v = append(*v, int(val))
 	}
+	return err
+}
+
+type countValue int
+
+// CountVar wraps an integer which counts occurrences of a flag, such as "-vvv"
+// for a verbosity of 3. Each occurrence without a value increments the count.
+// An explicit numeric value sets the count directly, e.g. "--verbose=4", and
+// an explicit false value resets it to zero.
+func CountVar(v *int) Value { return (*countValue)(v) }
+
+func (v *countValue) IsBoolean() bool   { return true }
+func (v *countValue) IsAggregate() bool { return true }
+func (v *countValue) String() string    { return strconv.Itoa(int(*v)) }
+func (v *countValue) Set(s string) error {
+	if val, err := strconv.ParseInt(s, 0, strconv.IntSize); err == nil {
+		*v = countValue(val)
+		return nil
+	}
+
+	val, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is neither a count nor a boolean", s)
+	}
+	if val {
+		*v++
+	} else {
+		*v = 0
+	}
 	return nil
 }
 
@@ -103,6 +194,21 @@ func (v *int64Value) Set(s string) error {
 	return err
 }
 
+type int64SliceValue []int64
+
+// Int64sVar wraps a slice of 64-bit signed integers.
+func Int64sVar(v *[]int64) Value { return (*int64SliceValue)(v) }
+
+func (v *int64SliceValue) IsAggregate() bool { return true }
+func (v *int64SliceValue) String() string    { return fmt.Sprintf("%v", *v) }
+func (v *int64SliceValue) Set(s string) error {
+	val, err := strconv.ParseInt(s, 0, 64)
+	if err == nil {
+		*v = append(*v, val)
+	}
+	return err
+}
+
 type uintValue uint
 
 // UintVar wraps an unsigned integer with machine-dependent bit width.
@@ -117,6 +223,21 @@ func (v *uintValue) Set(s string) error {
 	return err
 }
 
+type uintSliceValue []uint
+
+// UintsVar wraps a slice of unsigned integers.
+func UintsVar(v *[]uint) Value { return (*uintSliceValue)(v) }
+
+func (v *uintSliceValue) IsAggregate() bool { return true }
+func (v *uintSliceValue) String() string    { return fmt.Sprintf("%v", *v) }
+func (v *uintSliceValue) Set(s string) error {
+	val, err := strconv.ParseUint(s, 0, strconv.IntSize)
+	if err == nil {
+		*v = append(*v, uint(val))
+	}
+	return err
+}
+
 type uint64Value uint64
 
 // Uint64Var wraps a 64-bit unsigned integer.
@@ -131,6 +252,21 @@ func (v *uint64Value) Set(s string) error {
 	return err
 }
 
+type uint64SliceValue []uint64
+
+// Uint64sVar wraps a slice of 64-bit unsigned integers.
+func Uint64sVar(v *[]uint64) Value { return (*uint64SliceValue)(v) }
+
+func (v *uint64SliceValue) IsAggregate() bool { return true }
+func (v *uint64SliceValue) String() string    { return fmt.Sprintf("%v", *v) }
+func (v *uint64SliceValue) Set(s string) error {
+	val, err := strconv.ParseUint(s, 0, 64)
+	if err == nil {
+		*v = append(*v, val)
+	}
+	return err
+}
+
 type float64Value float64
 
 // Float64Var wraps a double-precision floating point.
@@ -145,6 +281,21 @@ func (v *float64Value) Set(s string) error {
 	return err
 }
 
+type float64SliceValue []float64
+
+// Float64sVar wraps a slice of double-precision floating points.
+func Float64sVar(v *[]float64) Value { return (*float64SliceValue)(v) }
+
+func (v *float64SliceValue) IsAggregate() bool { return true }
+func (v *float64SliceValue) String() string    { return fmt.Sprintf("%v", *v) }
+func (v *float64SliceValue) Set(s string) error {
+	val, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		*v = append(*v, val)
+	}
+	return err
+}
+
 type durationValue time.Duration
 
 // DurationVar wraps a time duration, including units.
@@ -158,3 +309,206 @@ func (v *durationValue) Set(s string) error {
 	}
 	return err
 }
+
+type durationSliceValue []time.Duration
+
+// DurationsVar wraps a slice of time durations, including units.
+func DurationsVar(v *[]time.Duration) Value { return (*durationSliceValue)(v) }
+
+func (v *durationSliceValue) IsAggregate() bool { return true }
+func (v *durationSliceValue) String() string    { return fmt.Sprintf("%v", *v) }
+func (v *durationSliceValue) Set(s string) error {
+	val, err := time.ParseDuration(s)
+	if err == nil {
+		*v = append(*v, val)
+	}
+	return err
+}
+
+type genericValue[T any] struct {
+	value *T
+	parse func(s string) (T, error)
+}
+
+// Var wraps a value of any type, set by a parse function. For example,
+// Var(&u, uuid.Parse) accepts UUIDs.
+func Var[T any](v *T, parse func(s string) (T, error)) Value { return &genericValue[T]{v, parse} }
+
+func (v *genericValue[T]) String() string { return fmt.Sprint(*v.value) }
+func (v *genericValue[T]) Set(s string) error {
+	val, err := v.parse(s)
+	if err == nil {
+		*v.value = val
+	}
+	return err
+}
+
+type genericSliceValue[T any] struct {
+	value *[]T
+	parse func(s string) (T, error)
+}
+
+// SliceVar wraps a slice of any type, each element set by a parse function.
+func SliceVar[T any](v *[]T, parse func(s string) (T, error)) Value {
+	return &genericSliceValue[T]{v, parse}
+}
+
+func (v *genericSliceValue[T]) IsAggregate() bool { return true }
+func (v *genericSliceValue[T]) String() string    { return fmt.Sprintf("%v", *v.value) }
+func (v *genericSliceValue[T]) Set(s string) error {
+	val, err := v.parse(s)
+	if err == nil {
+		*v.value = append(*v.value, val)
+	}
+	return err
+}
+
+type textValue struct{ value encoding.TextUnmarshaler }
+
+// TextVar wraps a value which can unmarshal itself from text, such as a
+// net.IP. The value is displayed with MarshalText if it's also an
+// encoding.TextMarshaler, and is otherwise formatted as with fmt.Print. Values
+// may implement BooleanValue or AggregateValue to be parsed as such.
+func TextVar(v encoding.TextUnmarshaler) Value { return textValue{v} }
+
+func (v textValue) IsBoolean() bool {
+	b, ok := v.value.(BooleanValue)
+	return ok && b.IsBoolean()
+}
+
+func (v textValue) IsAggregate() bool {
+	agg, ok := v.value.(AggregateValue)
+	return ok && agg.IsAggregate()
+}
+
+func (v textValue) String() string {
+	if m, ok := v.value.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	if s, ok := v.value.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprint(reflect.Indirect(reflect.ValueOf(v.value)))
+}
+
+func (v textValue) Set(s string) error { return v.value.UnmarshalText([]byte(s)) }
+
+type stringMapValue struct {
+	mapKeys
+	value *map[string]string
+}
+
+// StringMapVar wraps a map of strings, set from "key=value" pairs. Each key may
+// only be given once.
+func StringMapVar(v *map[string]string) Value { return &stringMapValue{value: v} }
+
+func (v *stringMapValue) IsAggregate() bool   { return true }
+func (v *stringMapValue) placeholder() string { return "KEY=VALUE" }
+func (v *stringMapValue) String() string      { return fmt.Sprintf("%v", *v.value) }
+func (v *stringMapValue) Set(s string) error {
+	key, val, err := v.splitPair(s)
+	if err != nil {
+		return err
+	}
+	if *v.value == nil {
+		*v.value = map[string]string{}
+	}
+	v.add(key)
+	(*v.value)[key] = val
+	return nil
+}
+
+type intMapValue struct {
+	mapKeys
+	value *map[string]int
+}
+
+// IntMapVar wraps a map of integers, set from "key=value" pairs. Each key may
+// only be given once.
+func IntMapVar(v *map[string]int) Value { return &intMapValue{value: v} }
+
+func (v *intMapValue) IsAggregate() bool   { return true }
+func (v *intMapValue) placeholder() string { return "KEY=VALUE" }
+func (v *intMapValue) String() string      { return fmt.Sprintf("%v", *v.value) }
+func (v *intMapValue) Set(s string) error {
+	key, str, err := v.splitPair(s)
+	if err != nil {
+		return err
+	}
+	val, err := strconv.ParseInt(str, 0, strconv.IntSize)
+	if err != nil {
+		return err
+	}
+	if *v.value == nil {
+		*v.value = map[string]int{}
+	}
+	v.add(key)
+	(*v.value)[key] = int(val)
+	return nil
+}
+
+type float64MapValue struct {
+	mapKeys
+	value *map[string]float64
+}
+
+// Float64MapVar wraps a map of double-precision floating points, set from
+// "key=value" pairs. Each key may only be given once.
+func Float64MapVar(v *map[string]float64) Value { return &float64MapValue{value: v} }
+
+func (v *float64MapValue) IsAggregate() bool   { return true }
+func (v *float64MapValue) placeholder() string { return "KEY=VALUE" }
+func (v *float64MapValue) String() string      { return fmt.Sprintf("%v", *v.value) }
+func (v *float64MapValue) Set(s string) error {
+	key, str, err := v.splitPair(s)
+	if err != nil {
+		return err
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return err
+	}
+	if *v.value == nil {
+		*v.value = map[string]float64{}
+	}
+	v.add(key)
+	(*v.value)[key] = val
+	return nil
+}
+
+// mapKeys tracks the keys given to a map value during a single parse. Keys
+// already in the map beforehand, such as preset entries, may be replaced.
+type mapKeys struct {
+	seen map[string]bool
+}
+
+func (k *mapKeys) reset() { k.seen = nil }
+
+// splitPair splits a "key=value" pair. Keys must be non-empty and not already
+// given during this parse. Keys are only recorded by add, once their values
+// are known to be valid.
+func (k *mapKeys) splitPair(s string) (key, value string, err error) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", "", errors.New("expected KEY=VALUE")
+	}
+
+	key, value = s[:i], s[i+1:]
+	if key == "" {
+		return "", "", errors.New("empty key")
+	}
+	if k.seen[key] {
+		return "", "", fmt.Errorf("duplicate key %q", key)
+	}
+	return key, value, nil
+}
+
+// add records a key as given.
+func (k *mapKeys) add(key string) {
+	if k.seen == nil {
+		k.seen = map[string]bool{}
+	}
+	k.seen[key] = true
+}